@@ -0,0 +1,373 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// AIState is what an enemy is doing this turn.
+type AIState int
+
+const (
+	Wandering AIState = iota
+	Patrolling
+	Guarding
+	Chasing
+	Searching
+	Fleeing
+)
+
+var aiStateNames = [...]string{"wander", "patrol", "guard", "chase", "search", "flee"}
+
+func (s AIState) String() string {
+	if s < 0 || int(s) >= len(aiStateNames) {
+		return fmt.Sprintf("AIState(%d)", int(s))
+	}
+	return aiStateNames[s]
+}
+
+// Archetype configures how an enemy behaves. Idle is the state it starts in
+// and goes back to once it gives up on the player: Wandering, Patrolling or
+// Guarding.
+type Archetype struct {
+	Name        string
+	Idle        AIState
+	Radius      int // how far from home it wanders or patrols
+	Waypoints   int // stops on a patrol route
+	FleeHealth  int // runs away below this health, 0 never does
+	SearchTurns int // turns spent looking around where the player was last seen
+	AlertRadius int // enemies this close join in when it notices the player
+	Weight      int // how often it is picked at spawn, relative to the others
+	OpensDoors  bool
+}
+
+var Archetypes = []*Archetype{
+	{Name: "wanderer", Idle: Wandering, Radius: 10, SearchTurns: 5, AlertRadius: 4, Weight: 4, OpensDoors: true},
+	{Name: "patrol", Idle: Patrolling, Radius: 12, Waypoints: 4, FleeHealth: 20, SearchTurns: 10, AlertRadius: 8, Weight: 3, OpensDoors: true},
+	{Name: "guard", Idle: Guarding, SearchTurns: 3, AlertRadius: 6, Weight: 2, OpensDoors: true},
+	{Name: "coward", Idle: Wandering, Radius: 6, FleeHealth: 50, AlertRadius: 10, Weight: 1},
+}
+
+func pickArchetype(rng *rand.Rand) *Archetype {
+	total := 0
+	for _, a := range Archetypes {
+		total += a.Weight
+	}
+	n := rng.Intn(total)
+	for _, a := range Archetypes {
+		if n < a.Weight {
+			return a
+		}
+		n -= a.Weight
+	}
+	return Archetypes[0]
+}
+
+// SetArchetype gives the enemy a behaviour, planning a patrol route around
+// where it stands now if it needs one.
+func (enemy *Enemy) SetArchetype(level *Level, archetype *Archetype) {
+	enemy.Archetype = archetype
+	enemy.home = enemy.Pos
+	enemy.route = nil
+	enemy.waypoint = 0
+	if archetype.Idle == Patrolling {
+		enemy.route = append(enemy.route, enemy.Pos)
+		stops := reachableWithin(level, enemy.Pos, archetype.Radius)
+		for i := 0; i < archetype.Waypoints && len(stops) > 0; i++ {
+			j := level.rng.Intn(len(stops))
+			enemy.route = append(enemy.route, stops[j])
+			stops = append(stops[:j], stops[j+1:]...)
+		}
+	}
+	enemy.setState(archetype.Idle)
+}
+
+// reachableWithin lists the floor tiles an enemy at start can walk to in at
+// most radius steps.
+func reachableWithin(level *Level, start Position, radius int) []Position {
+	distance := map[Position]int{start: 0}
+	frontier := []Position{start}
+	var tiles []Position
+	for len(frontier) > 0 {
+		current := frontier[0]
+		frontier = frontier[1:]
+		if distance[current] == radius {
+			continue
+		}
+		ns, _ := getNeighbors(level, current)
+		for _, next := range ns {
+			if _, ok := distance[next]; ok {
+				continue
+			}
+			distance[next] = distance[current] + 1
+			frontier = append(frontier, next)
+			if level.getTileType(next) == Floor {
+				tiles = append(tiles, next)
+			}
+		}
+	}
+	return tiles
+}
+
+func (enemy *Enemy) setState(state AIState) {
+	if enemy.State != state {
+		enemy.path = nil
+	}
+	enemy.State = state
+}
+
+// sees reports whether the enemy notices the player: close enough for how
+// brightly the player's surroundings are lit, with nothing solid in
+// between.
+func (enemy *Enemy) sees(level *Level) bool {
+	player := level.Player
+	return float64(enemy.distanceToCharacter(&player.Character)) < detectionRange(level.exposure) &&
+		hasLineOfSight(level, enemy.Pos, player.Pos)
+}
+
+func (enemy *Enemy) hurt() bool {
+	return enemy.Health < enemy.Archetype.FleeHealth
+}
+
+// alert sends idle enemies within earshot to where the player was seen.
+func (enemy *Enemy) alert(level *Level) {
+	called := false
+	for _, other := range level.Enemies {
+		if other == enemy || other.IsDead || other.State == Chasing || other.State == Fleeing ||
+			other.distanceToCharacter(&enemy.Character) > enemy.Archetype.AlertRadius {
+			continue
+		}
+		other.investigate(enemy.lastKnown)
+		called = true
+	}
+	if called {
+		fmt.Fprintln(Log, enemy.Name, "called for help!")
+	}
+}
+
+func (enemy *Enemy) investigate(pos Position) {
+	enemy.setState(Searching)
+	enemy.lastKnown = pos
+	enemy.searchLeft = enemy.Archetype.SearchTurns
+}
+
+// think moves the enemy between states for this turn.
+func (enemy *Enemy) think(level *Level) {
+	if enemy.sees(level) {
+		enemy.lastKnown = level.Player.Pos
+		if enemy.State != Chasing && enemy.State != Fleeing {
+			fmt.Fprintln(Log, enemy.Name, "noticed you!")
+			enemy.alert(level)
+		}
+		if enemy.hurt() {
+			enemy.setState(Fleeing)
+		} else {
+			enemy.setState(Chasing)
+		}
+		return
+	}
+	switch enemy.State {
+	case Chasing:
+		enemy.investigate(enemy.lastKnown)
+	case Fleeing:
+		enemy.setState(enemy.Archetype.Idle)
+	}
+}
+
+// act carries out the current state.
+func (enemy *Enemy) act(level *Level) {
+	switch enemy.State {
+	case Chasing:
+		enemy.moveTo(level, enemy.chaseGoal(level))
+	case Fleeing:
+		if !enemy.flee(level) {
+			enemy.attack(level)
+		}
+	case Searching:
+		if enemy.Pos != enemy.lastKnown && enemy.moveTo(level, enemy.lastKnown) {
+			return
+		}
+		// Arrived, or there is no way there: look around instead.
+		enemy.lastKnown = enemy.Pos
+		if enemy.searchLeft <= 0 {
+			enemy.setState(enemy.Archetype.Idle)
+			return
+		}
+		enemy.searchLeft--
+		if ns, _ := getNeighbors(level, enemy.Pos); len(ns) > 0 {
+			enemy.Move(ns[level.rng.Intn(len(ns))], level)
+		}
+	case Wandering:
+		if len(enemy.path) == 0 {
+			goal := getRandomPositionInsideCircle(level.rng, enemy.Archetype.Radius, enemy.Pos)
+			enemy.path = astar(level, enemy.Pos, goal, enemy.pathRules())
+		}
+		enemy.walk(level)
+	case Patrolling:
+		if len(enemy.route) == 0 {
+			return
+		}
+		if enemy.Pos == enemy.route[enemy.waypoint] || !enemy.moveTo(level, enemy.route[enemy.waypoint]) {
+			enemy.waypoint = (enemy.waypoint + 1) % len(enemy.route)
+		}
+	case Guarding:
+		if enemy.Pos != enemy.home {
+			enemy.moveTo(level, enemy.home)
+		}
+	}
+}
+
+// attack hits the player if they are next to the enemy.
+func (enemy *Enemy) attack(level *Level) bool {
+	ns, _ := getNeighbors(level, enemy.Pos)
+	for _, pos := range ns {
+		if pos == level.Player.Pos {
+			damage := int(enemy.Level)
+			fmt.Fprintln(Log, enemy.Name, "attacked", level.Player.Name, "for", damage, "damage!")
+			level.Player.Health -= damage
+			level.addAttack(&enemy.Character, &level.Player.Character, damage)
+			if level.Player.Health <= 0 {
+				fmt.Fprintln(Log, level.Player.Name, "is dead.")
+				level.Player.IsDead = true
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// flee steps to the neighbour furthest from the player, and reports false
+// when every step would bring it closer.
+func (enemy *Enemy) flee(level *Level) bool {
+	player := level.Player.Pos
+	best := enemy.Pos
+	bestDistance := abs(enemy.Pos.X-player.X) + abs(enemy.Pos.Y-player.Y)
+	ns, _ := getNeighbors(level, enemy.Pos)
+	for _, pos := range ns {
+		if d := abs(pos.X-player.X) + abs(pos.Y-player.Y); d > bestDistance {
+			best, bestDistance = pos, d
+		}
+	}
+	if best == enemy.Pos {
+		return false
+	}
+	enemy.Move(best, level)
+	return true
+}
+
+// moveTo takes a step towards goal, planning a new path when the goal has
+// changed, and reports false when it cannot get any closer.
+func (enemy *Enemy) moveTo(level *Level, goal Position) bool {
+	if len(enemy.path) == 0 || enemy.path[len(enemy.path)-1] != goal {
+		enemy.path = astar(level, enemy.Pos, goal, enemy.pathRules())
+	}
+	return enemy.walk(level)
+}
+
+func (enemy *Enemy) pathRules() pathRules {
+	return pathRules{opensDoors: enemy.Archetype.OpensDoors, throughAllies: true}
+}
+
+// reserve claims pos for the enemy until the end of the turn.
+func (level *Level) reserve(pos Position, enemy *Enemy) {
+	if level.reserved == nil {
+		level.reserved = make(map[Position]*Enemy)
+	}
+	level.reserved[pos] = enemy
+}
+
+// chaseGoal picks the free tile next to the player closest to the enemy
+// that no other enemy has taken this turn, so a pack surrounds the player
+// instead of queueing for the same tile. When every side is taken it heads
+// for the player anyway and waits its turn.
+func (enemy *Enemy) chaseGoal(level *Level) Position {
+	player := level.Player.Pos
+	goal := player
+	best := math.MaxInt
+	for _, o := range neighborOffsets {
+		slot := Position{player.X + o.X, player.Y + o.Y}
+		if !isOpenTile(level, slot) {
+			continue
+		}
+		if other := level.reserved[slot]; other != nil && other != enemy {
+			continue
+		}
+		if exists, other := hasEnemy(slot, level); exists && other != enemy {
+			continue
+		}
+		if d := abs(slot.X-enemy.Pos.X) + abs(slot.Y-enemy.Pos.Y); d < best {
+			goal, best = slot, d
+		}
+	}
+	if goal != player {
+		level.reserve(goal, enemy)
+	}
+	return goal
+}
+
+// busy reports whether the enemy is after the player rather than going
+// about its idle routine.
+func (enemy *Enemy) busy() bool {
+	return enemy.State == Chasing || enemy.State == Searching || enemy.State == Fleeing
+}
+
+// canSwap reports whether the enemy may trade places with an ally in its
+// way: idle allies make room, as do allies heading straight for the
+// enemy's tile. An ally that already moved this turn stays put.
+func (enemy *Enemy) canSwap(level *Level, ally *Enemy) bool {
+	if level.reserved[ally.Pos] == ally {
+		return false
+	}
+	return !ally.busy() || (len(ally.path) > 0 && ally.path[0] == enemy.Pos)
+}
+
+func (enemy *Enemy) swap(level *Level, ally *Enemy) {
+	if len(ally.path) > 0 && ally.path[0] == enemy.Pos {
+		ally.path = ally.path[1:]
+	} else {
+		ally.path = nil
+	}
+	level.occupants[level.index(enemy.Pos)] = ally
+	level.occupants[level.index(ally.Pos)] = enemy
+	enemy.Pos, ally.Pos = ally.Pos, enemy.Pos
+	level.reserve(enemy.Pos, enemy)
+	level.reserve(ally.Pos, ally)
+}
+
+// walk follows the current path one step, forgetting it when blocked. A
+// closed door on the way takes the turn to open. An ally in the way is
+// swapped with when it can be, otherwise the enemy waits for it to move on.
+func (enemy *Enemy) walk(level *Level) bool {
+	if len(enemy.path) == 0 {
+		return false
+	}
+	next := enemy.path[0]
+	if isClosedDoor(level, next) {
+		if !enemy.Archetype.OpensDoors || !canOpen(level, next) {
+			enemy.path = nil
+			return false
+		}
+		checkDoor(next, level)
+		return true
+	}
+	if exists, ally := hasEnemy(next, level); exists {
+		if enemy.canSwap(level, ally) {
+			enemy.swap(level, ally)
+			enemy.path = enemy.path[1:]
+		} else {
+			// Plan again next turn, there may be a way around by then.
+			enemy.path = nil
+		}
+		return true
+	}
+	from := enemy.Pos
+	enemy.Move(next, level)
+	if enemy.Pos == from {
+		enemy.path = nil
+		return false
+	}
+	level.reserve(next, enemy)
+	enemy.path = enemy.path[1:]
+	return true
+}
@@ -0,0 +1,225 @@
+package game
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+// aiLevel loads a level with the player on '@' and an enemy on every 'E'.
+func aiLevel(t *testing.T, torch bool, rows ...string) *Level {
+	t.Helper()
+	Log = io.Discard
+	level := levelFromASCII(t, rows...)
+	level.Player = NewPlayer("player", 1, *level.PlayerSpawn)
+	if torch {
+		level.Player.Light = &LightSource{Radius: torchRadius, Intensity: 1, Color: TorchColor}
+	}
+	for _, pos := range level.EnemySpawns {
+		level.AddEnemy(NewEnemy("enemy", 5, pos))
+	}
+	updateLighting(level)
+	return level
+}
+
+func TestEnemyAlertsOthers(t *testing.T) {
+	level := aiLevel(t, true,
+		"############",
+		"#@..E#.....#",
+		"#....#..E..#",
+		"#*...#.....#",
+		"############",
+	)
+	seer, other := level.Enemies[0], level.Enemies[1]
+	seer.Update(level)
+	if seer.State != Chasing {
+		t.Errorf("enemy in sight of the player is %s, want chase", seer.State)
+	}
+	if other.State != Searching || other.lastKnown != level.Player.Pos {
+		t.Errorf("enemy behind the wall is %s towards %v, want search towards %v", other.State, other.lastKnown, level.Player.Pos)
+	}
+}
+
+func TestEnemyLosesPlayer(t *testing.T) {
+	level := aiLevel(t, false,
+		"#############",
+		"#@.........E#",
+		"#############",
+	)
+	enemy := level.Enemies[0]
+	enemy.SetArchetype(level, &Archetype{Name: "guard", Idle: Guarding, SearchTurns: 2})
+	enemy.setState(Chasing)
+	enemy.lastKnown = Position{7, 1}
+
+	enemy.Update(level)
+	if enemy.State != Searching || enemy.Pos != (Position{10, 1}) {
+		t.Fatalf("enemy is %s at %v, want search at {10 1}", enemy.State, enemy.Pos)
+	}
+	for i := 0; i < 20; i++ {
+		enemy.Update(level)
+	}
+	if enemy.State != Guarding || enemy.Pos != enemy.home {
+		t.Errorf("enemy is %s at %v, want back on guard at %v", enemy.State, enemy.Pos, enemy.home)
+	}
+}
+
+func TestEnemyFlees(t *testing.T) {
+	level := aiLevel(t, true,
+		"#########",
+		"#.......#",
+		"#.@E....#",
+		"#.......#",
+		"#########",
+	)
+	enemy := level.Enemies[0]
+	enemy.Archetype = &Archetype{Name: "coward", Idle: Wandering, FleeHealth: 50}
+	enemy.Health = 10
+	enemy.Update(level)
+	if enemy.State != Fleeing || enemy.Pos != (Position{4, 2}) {
+		t.Errorf("enemy is %s at %v, want flee to {4 2}", enemy.State, enemy.Pos)
+	}
+	if level.Player.Health != 100 {
+		t.Errorf("fleeing enemy attacked, player health %d", level.Player.Health)
+	}
+
+	level = aiLevel(t, true,
+		"#####",
+		"#.@E#",
+		"#####",
+	)
+	enemy = level.Enemies[0]
+	enemy.Archetype = &Archetype{Name: "coward", Idle: Wandering, FleeHealth: 50}
+	enemy.Health = 10
+	enemy.Update(level)
+	if enemy.State != Fleeing || level.Player.Health == 100 {
+		t.Errorf("cornered enemy is %s, player health %d, want it to fight back", enemy.State, level.Player.Health)
+	}
+}
+
+func TestEnemyPatrol(t *testing.T) {
+	level := aiLevel(t, false,
+		"#########",
+		"#E.....##",
+		"#########",
+		"#@......#",
+		"#########",
+	)
+	enemy := level.Enemies[0]
+	enemy.SetArchetype(level, &Archetype{Name: "patrol", Idle: Patrolling, Radius: 6, Waypoints: 1})
+	if len(enemy.route) != 2 || enemy.route[0] != (Position{1, 1}) {
+		t.Fatalf("route = %v, want to start from {1 1}", enemy.route)
+	}
+	enemy.route[1] = Position{6, 1}
+	visits := make(map[Position]int)
+	for i := 0; i < 30; i++ {
+		enemy.Update(level)
+		visits[enemy.Pos]++
+	}
+	if visits[Position{1, 1}] < 2 || visits[Position{6, 1}] < 2 {
+		t.Errorf("patrol visited %v, want both ends of the corridor more than once", visits)
+	}
+}
+
+func TestEnemyDoors(t *testing.T) {
+	rows := []string{
+		"#######",
+		"#@....#",
+		"###|###",
+		"#.....#",
+		"#.E...#",
+		"#######",
+	}
+	tests := []struct {
+		name       string
+		door       rune
+		opensDoors bool
+		opened     bool
+	}{
+		{"opens the door", '|', true, true},
+		{"cannot open doors", '|', false, false},
+		{"locked door", '=', true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows := append([]string(nil), rows...)
+			rows[2] = "###" + string(test.door) + "###"
+			level := aiLevel(t, false, rows...)
+			enemy := level.Enemies[0]
+			enemy.Archetype = &Archetype{Name: "test", Idle: Wandering, SearchTurns: 10, OpensDoors: test.opensDoors}
+			enemy.investigate(Position{3, 1})
+			for i := 0; i < 4; i++ {
+				enemy.Update(level)
+			}
+			if opened := !isClosedDoor(level, Position{3, 2}); opened != test.opened {
+				t.Errorf("door opened = %v, want %v", opened, test.opened)
+			}
+			if through := enemy.Pos.Y < 3; through != test.opened {
+				t.Errorf("enemy ended up at %v", enemy.Pos)
+			}
+		})
+	}
+}
+
+func TestChasersSurroundPlayer(t *testing.T) {
+	level := aiLevel(t, true,
+		"###########",
+		"#.........#",
+		"#.........#",
+		"#....@....#",
+		"#.........#",
+		"#E.E...E.E#",
+		"###########",
+	)
+	level.Player.Health = 1 << 20
+	for i := 0; i < 12; i++ {
+		level.reserved = nil
+		for _, enemy := range level.Enemies {
+			enemy.Update(level)
+		}
+	}
+	sides := make(map[Position]bool)
+	for _, enemy := range level.Enemies {
+		if d := abs(enemy.Pos.X-level.Player.Pos.X) + abs(enemy.Pos.Y-level.Player.Pos.Y); d != 1 {
+			t.Errorf("enemy at %v is %d steps from the player, want next to them", enemy.Pos, d)
+		}
+		sides[enemy.Pos] = true
+	}
+	if len(sides) != len(level.Enemies) {
+		t.Errorf("enemies share tiles: %v", sides)
+	}
+}
+
+func TestEnemySwapsWithIdleAlly(t *testing.T) {
+	level := aiLevel(t, false,
+		"###########",
+		"#@....E..E#",
+		"###########",
+	)
+	guard, searcher := level.Enemies[0], level.Enemies[1]
+	guard.SetArchetype(level, &Archetype{Name: "guard", Idle: Guarding})
+	searcher.Archetype = &Archetype{Name: "loner", Idle: Wandering, SearchTurns: 5}
+	searcher.investigate(Position{3, 1})
+	for i := 0; i < 8; i++ {
+		level.reserved = nil
+		for _, enemy := range level.Enemies {
+			enemy.Update(level)
+		}
+	}
+	if searcher.Pos.X >= 6 {
+		t.Errorf("searcher stuck behind the guard at %v", searcher.Pos)
+	}
+	if guard.Pos != guard.home {
+		t.Errorf("guard at %v, want back at its post %v", guard.Pos, guard.home)
+	}
+}
+
+func TestAIStateString(t *testing.T) {
+	if got := Fleeing.String(); got != "flee" {
+		t.Errorf("Fleeing = %q, want flee", got)
+	}
+	for _, s := range []AIState{-1, AIState(len(aiStateNames))} {
+		if got, want := s.String(), fmt.Sprintf("AIState(%d)", int(s)); got != want {
+			t.Errorf("%d printed as %q, want %q", int(s), got, want)
+		}
+	}
+}
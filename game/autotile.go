@@ -0,0 +1,145 @@
+package game
+
+// Neighbour bits used by the autotiler. The four cardinal bits alone give
+// the classic 16 wall shapes, the diagonal bits let thick walls render as
+// faces instead of junctions.
+const (
+	NeighborN uint8 = 1 << iota
+	NeighborE
+	NeighborS
+	NeighborW
+	NeighborNE
+	NeighborSE
+	NeighborSW
+	NeighborNW
+)
+
+const cardinalNeighbors = NeighborN | NeighborE | NeighborS | NeighborW
+
+type autotileRule struct {
+	mask     uint8
+	match    uint8
+	tileType TileType
+}
+
+// wallRules is checked top to bottom and the first rule where
+// flags&mask == match wins. Eight-neighbour rules come first so they can
+// override the cardinal shape for walls that are more than one tile thick.
+var wallRules = []autotileRule{
+	{0xff, 0xff, WallFill},
+	{0xff, 0xff &^ (NeighborN | NeighborNE | NeighborNW), Wall},
+	{0xff, 0xff &^ (NeighborS | NeighborSE | NeighborSW), Wall},
+	{0xff, 0xff &^ (NeighborW | NeighborNW | NeighborSW), WallNS},
+	{0xff, 0xff &^ (NeighborE | NeighborNE | NeighborSE), WallNS},
+	{0xff, 0xff &^ NeighborNE, WallNW},
+	{0xff, 0xff &^ NeighborNW, WallNE},
+	{0xff, 0xff &^ NeighborSE, WallSW},
+	{0xff, 0xff &^ NeighborSW, WallSE},
+
+	{cardinalNeighbors, 0, Wall},
+	{cardinalNeighbors, NeighborW, WallW},
+	{cardinalNeighbors, NeighborE, WallE},
+	{cardinalNeighbors, NeighborW | NeighborE, Wall},
+	{cardinalNeighbors, NeighborN, WallN},
+	{cardinalNeighbors, NeighborN | NeighborW, WallNE},
+	{cardinalNeighbors, NeighborN | NeighborE, WallNW},
+	{cardinalNeighbors, NeighborN | NeighborW | NeighborE, WallNWE},
+	{cardinalNeighbors, NeighborS, WallS},
+	{cardinalNeighbors, NeighborS | NeighborW, WallSE},
+	{cardinalNeighbors, NeighborS | NeighborE, WallSW},
+	{cardinalNeighbors, NeighborS | NeighborW | NeighborE, WallSWE},
+	{cardinalNeighbors, NeighborN | NeighborS, WallNS},
+	{cardinalNeighbors, NeighborN | NeighborS | NeighborW, WallNSE},
+	{cardinalNeighbors, NeighborN | NeighborS | NeighborE, WallNSW},
+	{cardinalNeighbors, cardinalNeighbors, WallNSWE},
+}
+
+// doorRules pick the door orientation from the walls it sits between. The
+// open or closed state is kept, see orientDoor.
+var doorRules = []autotileRule{
+	{NeighborW | NeighborE, NeighborW | NeighborE, ClosedDoorH},
+	{NeighborN | NeighborS, NeighborN | NeighborS, ClosedDoorV},
+}
+
+func matchRule(rules []autotileRule, flags uint8) (TileType, bool) {
+	for _, r := range rules {
+		if flags&r.mask == r.match {
+			return r.tileType, true
+		}
+	}
+	return Blank, false
+}
+
+func isWallOrDoor(level *Level, pos Position) bool {
+	if !level.inBounds(pos) {
+		return false
+	}
+	return isWall(level, pos) || isDoor(level, pos)
+}
+
+// getWallNeighbors returns the neighbour bits of pos that hold a wall or a
+// door. A diagonal only counts when both cardinals next to it are set, so a
+// wall corner touching another room diagonally keeps its shape.
+func getWallNeighbors(level *Level, pos Position) uint8 {
+	var flags uint8
+	offsets := [...]struct {
+		dx, dy int
+		bit    uint8
+	}{
+		{0, -1, NeighborN},
+		{1, 0, NeighborE},
+		{0, 1, NeighborS},
+		{-1, 0, NeighborW},
+		{1, -1, NeighborNE},
+		{1, 1, NeighborSE},
+		{-1, 1, NeighborSW},
+		{-1, -1, NeighborNW},
+	}
+	for _, o := range offsets {
+		if isWallOrDoor(level, Position{pos.X + o.dx, pos.Y + o.dy}) {
+			flags |= o.bit
+		}
+	}
+	diagonals := [...]struct{ bit, a, b uint8 }{
+		{NeighborNE, NeighborN, NeighborE},
+		{NeighborSE, NeighborS, NeighborE},
+		{NeighborSW, NeighborS, NeighborW},
+		{NeighborNW, NeighborN, NeighborW},
+	}
+	for _, d := range diagonals {
+		if flags&d.a == 0 || flags&d.b == 0 {
+			flags &^= d.bit
+		}
+	}
+	return flags
+}
+
+func orientDoor(t, closedType TileType) TileType {
+	open := t == OpenDoorH || t == OpenDoorV
+	switch {
+	case closedType == ClosedDoorH && open:
+		return OpenDoorH
+	case closedType == ClosedDoorV && open:
+		return OpenDoorV
+	}
+	return closedType
+}
+
+// Autotile picks the wall and door sprites of the whole level from their
+// neighbours, so maps only have to say where walls and doors are.
+func Autotile(level *Level) {
+	for y := 0; y < level.Height; y++ {
+		for x := 0; x < level.Width; x++ {
+			pos := Position{x, y}
+			if isWall(level, pos) {
+				if t, ok := matchRule(wallRules, getWallNeighbors(level, pos)); ok {
+					level.setTileType(pos, t)
+				}
+			} else if isDoor(level, pos) {
+				if t, ok := matchRule(doorRules, getWallNeighbors(level, pos)); ok {
+					level.setTileType(pos, orientDoor(level.getTileType(pos), t))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package game
+
+import "testing"
+
+func TestWallRules(t *testing.T) {
+	const (
+		n = NeighborN
+		e = NeighborE
+		s = NeighborS
+		w = NeighborW
+	)
+	want := map[uint8]TileType{
+		0:             Wall,
+		n:             WallN,
+		e:             WallE,
+		n | e:         WallNW,
+		s:             WallS,
+		n | s:         WallNS,
+		e | s:         WallSW,
+		n | e | s:     WallNSW,
+		w:             WallW,
+		n | w:         WallNE,
+		e | w:         Wall,
+		n | e | w:     WallNWE,
+		s | w:         WallSE,
+		n | s | w:     WallNSE,
+		e | s | w:     WallSWE,
+		n | e | s | w: WallNSWE,
+	}
+	for flags := uint8(0); flags < 16; flags++ {
+		if got, ok := matchRule(wallRules, flags); !ok || got != want[flags] {
+			t.Errorf("wall with neighbours %04b = %s, want %s", flags, got, want[flags])
+		}
+	}
+	t11, _ := matchRule(wallRules, 11)
+	t15, _ := matchRule(wallRules, 15)
+	if t11 == t15 {
+		t.Errorf("T junction and cross junction are both %s", t11)
+	}
+}
@@ -1,11 +1,5 @@
 package game
 
-import "github.com/veandco/go-sdl2/sdl"
-
-type Drawable interface {
-	Draw(renderer *sdl.Renderer)
-}
-
 type Movable interface {
 	Move(pos Position)
 }
@@ -17,12 +11,12 @@ type Character struct {
 	Health      int
 	IsDead      bool
 	SightRadius int
-	texture     *sdl.Texture
-	TextureRect sdl.Rect
+	Light       *LightSource
 }
 
 func (e *Character) Move(pos Position, level *Level) {
 	if canMove(pos, level) {
+		level.moveOccupant(e, pos)
 		e.Pos = pos
 	}
 }
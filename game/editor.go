@@ -0,0 +1,34 @@
+package game
+
+// RevealLevel marks every tile as seen and fully lit, for tools that need
+// to show the whole map.
+func RevealLevel(level *Level) {
+	level.resetVisibility(true)
+	for y := 0; y < level.Height; y++ {
+		for x := 0; x < level.Width; x++ {
+			level.setVisited(Position{x, y}, true)
+		}
+	}
+	for i := range level.light {
+		level.light[i] = Light{}
+		level.light[i].add(1, LightColor{255, 255, 255})
+	}
+}
+
+// PreviewFOV shows the level as a player standing at pos with a torch
+// would see it.
+func PreviewFOV(level *Level, pos Position, sightRadius int) {
+	viewer := &Player{}
+	viewer.Pos = pos
+	viewer.SightRadius = sightRadius
+	viewer.Light = &LightSource{Radius: torchRadius, Intensity: 1, Color: TorchColor}
+	// Forgetting tiles needs no redraw, only seeing them again does.
+	for i := range level.visited {
+		level.visited[i] = false
+	}
+	player := level.Player
+	level.Player = viewer
+	checkVisibility(level, &viewer.Character)
+	updateLighting(level)
+	level.Player = player
+}
@@ -1,39 +1,30 @@
 package game
 
-import (
-	"fmt"
-	"math"
-
-	"github.com/veandco/go-sdl2/sdl"
-)
+import "math"
 
 type Enemy struct {
-	Aggressive bool
 	Character
-	path []Position
+	Archetype  *Archetype
+	State      AIState
+	path       []Position
+	home       Position
+	route      []Position
+	waypoint   int
+	lastKnown  Position
+	searchLeft int
 }
 
-func NewEnemy(name string, level float64, pos Position, texture *sdl.Texture, textureRect *sdl.Rect) *Enemy {
+func NewEnemy(name string, level float64, pos Position) *Enemy {
 	var newEnemy Enemy
 	newEnemy.Name = name
 	newEnemy.Level = level
 	newEnemy.Pos = pos
 	newEnemy.Health = 100
-	newEnemy.texture = texture
-	newEnemy.TextureRect = *textureRect
+	newEnemy.Archetype = Archetypes[0]
+	newEnemy.home = pos
 	return &newEnemy
 }
 
-func (enemy *Enemy) Draw(renderer *sdl.Renderer, tileSize, offsetX, offsetY int32) {
-	enemyDestRect := sdl.Rect{
-		X: int32(enemy.Pos.X)*tileSize + offsetX,
-		Y: int32(enemy.Pos.Y)*tileSize + offsetY,
-		W: int32(tileSize),
-		H: int32(tileSize),
-	}
-	renderer.Copy(enemy.texture, &enemy.TextureRect, &enemyDestRect)
-}
-
 func (enemy *Enemy) distanceToCharacter(character *Character) int {
 	dx := float64(enemy.Pos.X - character.Pos.X)
 	dy := float64(enemy.Pos.Y - character.Pos.Y)
@@ -41,40 +32,12 @@ func (enemy *Enemy) distanceToCharacter(character *Character) int {
 	return int(distance)
 }
 
+// Update lets the enemy notice the player, then fight if the player is next
+// to it or otherwise go about whatever its state calls for.
 func (enemy *Enemy) Update(level *Level) {
-	ns, _ := getNeighbors(level, enemy.Pos)
-	for _, pos := range ns {
-		if pos == level.Player.Pos {
-			damage := int(enemy.Level)
-			fmt.Println(enemy.Name, "attacked", level.Player.Name, "for", damage, "damage!")
-			return
-		}
-	}
-	if enemy.distanceToCharacter(&level.Player.Character) < 5 && !enemy.Aggressive {
-		fmt.Println(enemy.Name, "noticed you!")
-		enemy.Aggressive = true
-	}
-	if !enemy.Aggressive && enemy.path == nil {
-		enemy.path = astar(level, enemy.Pos, getRandomPositionInsideCircle(10, enemy.Pos))
-	}
-	if enemy.Aggressive {
-		enemy.path = astar(level, enemy.Pos, level.Player.Pos)
-	}
-	if enemy.path != nil && len(enemy.path) != 0 {
-		//for _, p := range enemy.path {
-		//	level.Debug[p] = true
-		//}
-
-		enemy.Move(enemy.path[0], level)
-		if enemy.Pos == enemy.path[len(enemy.path)-1] {
-			enemy.path = nil
-			return
-		}
-		if len(enemy.path) > 1 {
-			enemy.path = enemy.path[1:]
-			if len(enemy.path) == 0 {
-				enemy.path = nil
-			}
-		}
+	enemy.think(level)
+	if enemy.State != Fleeing && enemy.attack(level) {
+		return
 	}
+	enemy.act(level)
 }
@@ -2,20 +2,21 @@ package game
 
 import (
 	"fmt"
+	"io"
 	"math"
-	"math/rand"
+	"os"
 	"sort"
+	"time"
 
 	"github.com/wehard/ftapi"
-
-	"github.com/veandco/go-sdl2/sdl"
 )
 
+// Log receives the game's messages. Simulations set it to io.Discard.
+var Log io.Writer = os.Stdout
+
 type GameUI interface {
 	Draw(*Level)
 	GetInput() *Input
-	GetTextureIndex(TileType) *sdl.Rect
-	GetTextureAtlas() *sdl.Texture
 	NewCharacterLabel(character *Character)
 }
 
@@ -24,10 +25,14 @@ type Position struct {
 	Y int
 }
 
-type TileType string
+// TileType identifies a tile. The names in tileTypeNames are only used by
+// file formats and the texture index.
+type TileType uint8
 
 type Tile struct {
 	TileType TileType
+	// Locked doors stay shut when bumped into.
+	Locked bool
 }
 
 type priorityPos struct {
@@ -41,55 +46,146 @@ func (p priorityArray) Len() int           { return len(p) }
 func (p priorityArray) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
 func (p priorityArray) Less(i, j int) bool { return p[i].priority < p[j].priority }
 
+// Walls and doors are kept together so isWall and isDoor can check a range.
 const (
-	Blank       TileType = "blank"
-	Wall        TileType = "wall"
-	WallEW      TileType = "wall_ew"
-	WallSW      TileType = "wall_sw"
-	WallNS      TileType = "wall_ns"
-	WallNW      TileType = "wall_nw"
-	WallNE      TileType = "wall_ne"
-	WallSE      TileType = "wall_se"
-	WallN       TileType = "wall_n"
-	WallS       TileType = "wall_s"
-	WallE       TileType = "wall_e"
-	WallW       TileType = "wall_w"
-	WallSWE     TileType = "wall_swe"
-	WallNSW     TileType = "wall_nsw"
-	WallNSE     TileType = "wall_nse"
-	WallNWE     TileType = "wall_nwe"
-	Floor       TileType = "floor"
-	Hole        TileType = "hole"
-	ClosedDoorV TileType = "door_closed_v"
-	OpenDoorV   TileType = "door_open_v"
-	ClosedDoorH TileType = "door_closed_h"
-	OpenDoorH   TileType = "door_open_h"
-	ClosedChest TileType = "chest_closed"
-	OpenChest   TileType = "chest_open"
+	Blank TileType = iota
+	Wall
+	WallEW
+	WallSW
+	WallNS
+	WallNW
+	WallNE
+	WallSE
+	WallN
+	WallS
+	WallE
+	WallW
+	WallSWE
+	WallNSW
+	WallNSE
+	WallNWE
+	WallNSWE
+	WallFill
+	Floor
+	Hole
+	ClosedDoorV
+	OpenDoorV
+	ClosedDoorH
+	OpenDoorH
+	Brazier
+	ClosedChest
+	OpenChest
+	tileTypeCount
 )
 
+var tileTypeNames = [tileTypeCount]string{
+	Blank:       "blank",
+	Wall:        "wall",
+	WallEW:      "wall_ew",
+	WallSW:      "wall_sw",
+	WallNS:      "wall_ns",
+	WallNW:      "wall_nw",
+	WallNE:      "wall_ne",
+	WallSE:      "wall_se",
+	WallN:       "wall_n",
+	WallS:       "wall_s",
+	WallE:       "wall_e",
+	WallW:       "wall_w",
+	WallSWE:     "wall_swe",
+	WallNSW:     "wall_nsw",
+	WallNSE:     "wall_nse",
+	WallNWE:     "wall_nwe",
+	WallNSWE:    "wall_nswe",
+	WallFill:    "wall_fill",
+	Floor:       "floor",
+	Hole:        "hole",
+	ClosedDoorV: "door_closed_v",
+	OpenDoorV:   "door_open_v",
+	ClosedDoorH: "door_closed_h",
+	OpenDoorH:   "door_open_h",
+	Brazier:     "brazier",
+	ClosedChest: "chest_closed",
+	OpenChest:   "chest_open",
+}
+
+func (t TileType) String() string {
+	if t >= tileTypeCount {
+		return "unknown"
+	}
+	return tileTypeNames[t]
+}
+
 type Path []Position
 
-func BreadthFirstSearch(level *Level, startPos Position) map[Position]bool {
+// BreadthFirstSearch returns the number of steps from startPos to every
+// passable tile connected to it. Closed doors are walked through and
+// characters are ignored.
+func BreadthFirstSearch(level *Level, startPos Position) map[Position]int {
 	frontier := make([]Position, 0, 8)
 	frontier = append(frontier, startPos)
-	visited := make(map[Position]bool)
-	visited[startPos] = true
+	distance := make(map[Position]int)
+	distance[startPos] = 0
 	for len(frontier) > 0 {
 		current := frontier[0]
 		frontier = frontier[1:]
-		ns, _ := getNeighbors(level, current)
-		for _, next := range ns {
-			if !visited[next] {
+		for _, o := range cardinalOffsets {
+			next := Position{current.X + o.X, current.Y + o.Y}
+			if _, seen := distance[next]; !seen && level.IsPassable(next) {
 				frontier = append(frontier, next)
-				visited[next] = true
+				distance[next] = distance[current] + 1
 			}
 		}
 	}
-	return visited
+	return distance
 }
 
-func astar(level *Level, start Position, goal Position) Path {
+// doorCost is the extra turn it takes to open a closed door on the way.
+const doorCost = 1
+
+// allyCost is the extra cost of planning through a tile another enemy
+// stands on, so paths go around crowds when there is room.
+const allyCost = 3
+
+// pathRules say which tiles besides free floor a path may use.
+type pathRules struct {
+	// opensDoors allows closed doors that are not locked.
+	opensDoors bool
+	// throughAllies allows tiles other enemies stand on, expecting them to
+	// move on or swap places by the time the path gets there.
+	throughAllies bool
+	// explored keeps the path to tiles the player has seen.
+	explored bool
+}
+
+// neighborOffsets are in the order getNeighbors returns its tiles.
+var neighborOffsets = []Position{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+
+// pathNeighbors are the tiles a path can step to from pos.
+func pathNeighbors(level *Level, pos Position, rules pathRules) []Position {
+	var ns []Position
+	if rules.throughAllies {
+		for _, o := range neighborOffsets {
+			next := Position{pos.X + o.X, pos.Y + o.Y}
+			if isOpenTile(level, next) {
+				ns = append(ns, next)
+			}
+		}
+	} else {
+		ns, _ = getNeighbors(level, pos)
+	}
+	if !rules.opensDoors {
+		return ns
+	}
+	for _, o := range cardinalOffsets {
+		next := Position{pos.X + o.X, pos.Y + o.Y}
+		if canOpen(level, next) {
+			ns = append(ns, next)
+		}
+	}
+	return ns
+}
+
+func astar(level *Level, start Position, goal Position, rules pathRules) Path {
 	var path Path
 	path = make(Path, 0)
 
@@ -121,9 +217,22 @@ func astar(level *Level, start Position, goal Position) Path {
 		}
 
 		frontier = frontier[1:]
-		ns, _ := getNeighbors(level, current.Position)
-		for _, next := range ns {
+		for _, next := range pathNeighbors(level, current.Position, rules) {
+			if level.Player != nil && next == level.Player.Pos && next != goal {
+				continue
+			}
+			if rules.explored && !level.IsVisited(next) {
+				continue
+			}
 			newCost := costSoFar[current.Position] + 1
+			if isClosedDoor(level, next) {
+				newCost += doorCost
+			}
+			if rules.throughAllies {
+				if exists, _ := hasEnemy(next, level); exists {
+					newCost += allyCost
+				}
+			}
 			_, exists := costSoFar[next]
 			if !exists || newCost < costSoFar[next] {
 				costSoFar[next] = newCost
@@ -138,6 +247,16 @@ func astar(level *Level, start Position, goal Position) Path {
 	return nil
 }
 
+// PlayerPath returns the steps that take the player to goal through the
+// part of the level they have explored, opening doors and going around
+// enemies, or nil when there is no such way.
+func PlayerPath(level *Level, goal Position) []Position {
+	if !level.IsVisited(goal) {
+		return nil
+	}
+	return astar(level, level.Player.Pos, goal, pathRules{opensDoors: true, explored: true})
+}
+
 func abs(x int) int {
 	switch {
 	case x < 0:
@@ -216,8 +335,8 @@ func checkVisibility(level *Level, character *Character) {
 		ps := bresenham(character.Pos, p)
 		for _, sp := range ps {
 			if sp.X >= 0 && sp.X < level.Width && sp.Y >= 0 && sp.Y < level.Height {
-				level.Visible[sp.Y][sp.X] = true
-				level.Visited[sp.Y][sp.X] = true
+				level.visible[level.index(sp)] = true
+				level.setVisited(sp, true)
 			}
 			if isSolid(level, sp) {
 				break
@@ -228,59 +347,171 @@ func checkVisibility(level *Level, character *Character) {
 
 var AuthorizedClientCredentials ftapi.ClientCredentials
 
-func Run(gameUI GameUI) {
+// Config selects the level, the enemy roster and the tuning of a game. Zero
+// values fall back to the defaults below.
+type Config struct {
+	MapFile     string
+	RosterFile  string
+	Seed        int64
+	EnemyCount  int
+	SightRadius int
+	RecordFile  string
+	// MinSpawnDistance is the fewest steps between the player and an enemy
+	// placed at random, SpawnDensity the floor tiles per enemy in a room.
+	MinSpawnDistance int
+	SpawnDensity     int
+}
+
+const (
+	defaultEnemyCount       = 50
+	defaultSightRadius      = 50
+	defaultMinSpawnDistance = 8
+	defaultSpawnDensity     = 12
+)
+
+// loadRoster returns the enemies' names and levels. Without a roster file
+// the dungeon is filled with generic enemies instead.
+func loadRoster(filename string) []ftapi.UserData {
+	var roster []ftapi.UserData
+	if filename != "" {
+		userData, err := ftapi.LoadUserData(filename)
+		if err != nil {
+			fmt.Fprintln(Log, "failed to load roster:", err)
+		}
+		for _, user := range userData {
+			if len(user.CursusUsers) > 0 {
+				roster = append(roster, user)
+			}
+		}
+	}
+	if len(roster) == 0 {
+		roster = []ftapi.UserData{
+			{Login: "rat", CursusUsers: []ftapi.CursusUser{{Level: 1}}},
+			{Login: "goblin", CursusUsers: []ftapi.CursusUser{{Level: 4}}},
+			{Login: "skeleton", CursusUsers: []ftapi.CursusUser{{Level: 7}}},
+			{Login: "wraith", CursusUsers: []ftapi.CursusUser{{Level: 12}}},
+		}
+	}
+	return roster
+}
 
-	userData, _ := ftapi.LoadUserData("game/users.json")
-	level := LoadLevelFromCSVFile("ui/assets/dungeon_csv_Wall.csv")
+func (config Config) withDefaults() Config {
+	if config.EnemyCount == 0 {
+		config.EnemyCount = defaultEnemyCount
+	}
+	if config.SightRadius == 0 {
+		config.SightRadius = defaultSightRadius
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+	if config.MinSpawnDistance == 0 {
+		config.MinSpawnDistance = defaultMinSpawnDistance
+	}
+	if config.SpawnDensity == 0 {
+		config.SpawnDensity = defaultSpawnDensity
+	}
+	return config
+}
+
+// Run plays a game until the UI asks to quit or the player dies. With
+// config.RecordFile set the seed and every input are saved so the game can
+// be replayed.
+func Run(gameUI GameUI, config Config) error {
+	config = config.withDefaults()
+	fmt.Fprintln(Log, "seed:", config.Seed)
+	if config.RecordFile == "" {
+		_, err := play(gameUI, config)
+		return err
+	}
+	rec := &recorder{GameUI: gameUI}
+	level, err := play(rec, config)
+	if err != nil {
+		return err
+	}
+	return writeReplay(config.RecordFile, replay{config, rec.inputs, Checksum(level)})
+}
+
+func play(gameUI GameUI, config Config) (*Level, error) {
+	roster := loadRoster(config.RosterFile)
+	level, err := LoadLevel(config.MapFile)
+	if err != nil {
+		return nil, err
+	}
+	level.SetSeed(config.Seed)
 
 	//playerUser := ftapi.GetAuthorizedUserData(AuthorizedClientCredentials.AccessToken)
 
-	level.Player = NewPlayer("player", 10.0, level.getRandomPosition(), gameUI.GetTextureAtlas(), gameUI.GetTextureIndex("player"))
-	level.Player.SightRadius = 50
+	var playerPos Position
+	if level.PlayerSpawn != nil {
+		playerPos = *level.PlayerSpawn
+	} else if playerPos, err = level.getRandomPosition(); err != nil {
+		return nil, err
+	}
+	level.Player = NewPlayer("player", 10.0, playerPos)
+	level.Player.SightRadius = config.SightRadius
+	level.Player.Light = &LightSource{Radius: torchRadius, Intensity: 1, Color: TorchColor}
 	gameUI.NewCharacterLabel(&level.Player.Character)
 
 	level.Enemies = make([]*Enemy, 0)
-	for i := 0; i < 50; i++ {
-		user := userData[rand.Intn(len(userData))]
-		if len(user.CursusUsers) == 0 {
-			fmt.Println("bad enemy")
+	spawns := newSpawner(level, config.MinSpawnDistance, config.SpawnDensity)
+	for i := 0; i < config.EnemyCount; i++ {
+		user := roster[level.rng.Intn(len(roster))]
+		userLevel := user.CursusUsers[0].Level
+		// A map spawn that is taken, by the player or an earlier enemy, or
+		// not walkable gets a random position instead.
+		var pos Position
+		if i < len(level.EnemySpawns) && canMove(level.EnemySpawns[i], level) && level.EnemySpawns[i] != level.Player.Pos {
+			pos = level.EnemySpawns[i]
+		} else if pos, err = spawns.next(); err != nil {
+			fmt.Fprintln(Log, err)
+			break
+		}
+		spawns.add(pos)
+		enemy := NewEnemy(user.Login, userLevel, pos)
+		enemy.SetArchetype(level, pickArchetype(level.rng))
+		if userLevel >= enemyGlowMinLevel {
+			enemy.Light = &LightSource{Radius: enemyGlowRadius, Intensity: 0.6, Color: GlowColor}
+		}
+		if !level.AddEnemy(enemy) {
 			continue
 		}
-		userLevel := user.CursusUsers[0].Level
-		pos := level.getRandomPosition()
-		enemy := NewEnemy(user.Login, userLevel, pos, gameUI.GetTextureAtlas(), gameUI.GetTextureIndex("enemy"))
-		level.Enemies = append(level.Enemies, enemy)
 		gameUI.NewCharacterLabel(&enemy.Character)
 	}
 
 	for {
-		// Clear dead enemies
-		for i := len(level.Enemies) - 1; i >= 0; i-- {
-			if level.Enemies[i].IsDead {
-				level.Enemies = append(level.Enemies[:i], level.Enemies[i+1:]...)
-			}
-		}
+		level.removeDeadEnemies()
+
+		updateLighting(level)
 
 		// Update enemies
+		level.reserved = make(map[Position]*Enemy)
 		for _, e := range level.Enemies {
-			if !e.IsDead {
+			if !e.IsDead && !level.Player.IsDead {
 				e.Update(level)
 			}
 		}
+		if level.Player.IsDead {
+			gameUI.Draw(level)
+			return level, nil
+		}
 
 		// Check visibility
 		checkVisibility(level, &level.Player.Character)
+		updateLighting(level)
 
 		gameUI.Draw(level)
 		input := gameUI.GetInput()
 		if input.Type == Quit {
-			return
+			return level, nil
 		}
 		if input.Type == Action {
 			checkHole(level.Player.Pos, level)
 			//level.Debug = make(map[Position]bool)
-			//astar(level, level.Player.Pos, getRandomPositionInsideCircle(5, level.Player.Pos))
+			//astar(level, level.Player.Pos, getRandomPositionInsideCircle(level.rng, 5, level.Player.Pos), pathRules{})
 		}
+		level.Turn++
+		level.Attacks = nil
 		handleInput(level, input)
 	}
 }
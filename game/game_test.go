@@ -0,0 +1,189 @@
+package game
+
+import "testing"
+
+func TestAstar(t *testing.T) {
+	level := levelFromASCII(t,
+		"###########",
+		"#....#....#",
+		"#.##.#....#",
+		"#..#......#",
+		"######|####",
+		"#...#.....#",
+		"###########",
+	)
+	tests := []struct {
+		name        string
+		start, goal Position
+		opensDoors  bool
+		length      int
+	}{
+		{"straight", Position{6, 1}, Position{9, 1}, false, 3},
+		{"around walls", Position{1, 1}, Position{9, 3}, false, 10},
+		{"start is goal", Position{1, 1}, Position{1, 1}, false, 0},
+		{"unreachable", Position{1, 1}, Position{2, 5}, false, -1},
+		{"goal in wall", Position{1, 1}, Position{5, 2}, false, -1},
+		{"goal behind closed door", Position{6, 3}, Position{6, 5}, false, -1},
+		{"door opened on the way", Position{6, 3}, Position{6, 5}, true, 2},
+		{"goal outside level", Position{1, 1}, Position{-3, 20}, false, -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := astar(level, test.start, test.goal, pathRules{opensDoors: test.opensDoors})
+			if test.length < 0 {
+				if path != nil {
+					t.Fatalf("path = %v, want none", path)
+				}
+				return
+			}
+			if len(path) != test.length {
+				t.Fatalf("path = %v, want %d steps", path, test.length)
+			}
+			prev := test.start
+			for _, p := range path {
+				if abs(p.X-prev.X)+abs(p.Y-prev.Y) != 1 {
+					t.Fatalf("step from %v to %v in %v", prev, p, path)
+				}
+				if !canMove(p, level) && !(test.opensDoors && canOpen(level, p)) {
+					t.Fatalf("path %v goes through %v", path, p)
+				}
+				prev = p
+			}
+			if test.length > 0 && prev != test.goal {
+				t.Errorf("path ends at %v, want %v", prev, test.goal)
+			}
+		})
+	}
+}
+
+func TestPlayerPath(t *testing.T) {
+	level := levelFromASCII(t,
+		"#########",
+		"#@..|...#",
+		"#.#######",
+		"#.......#",
+		"#########",
+	)
+	level.Player = NewPlayer("player", 1, *level.PlayerSpawn)
+	for x := 1; x < 8; x++ {
+		level.visited[level.index(Position{x, 1})] = true
+	}
+	level.visited[level.index(Position{1, 2})] = true
+	level.visited[level.index(Position{1, 3})] = true
+	level.visited[level.index(Position{7, 3})] = true
+
+	if path := PlayerPath(level, Position{7, 1}); len(path) != 6 || path[5] != (Position{7, 1}) {
+		t.Errorf("path through the door = %v, want 6 steps", path)
+	}
+	if path := PlayerPath(level, Position{6, 3}); path != nil {
+		t.Errorf("path to an unexplored tile = %v, want none", path)
+	}
+	if path := PlayerPath(level, Position{7, 3}); path != nil {
+		t.Errorf("path through unexplored tiles = %v, want none", path)
+	}
+	level.AddEnemy(NewEnemy("enemy", 1, Position{3, 1}))
+	if path := PlayerPath(level, Position{7, 1}); path != nil {
+		t.Errorf("path through an enemy = %v, want none", path)
+	}
+}
+
+func checkLine(t *testing.T, from, to Position) {
+	t.Helper()
+	points := bresenham(from, to)
+	if len(points) == 0 || points[0] != from || points[len(points)-1] != to {
+		t.Fatalf("bresenham(%v, %v) = %v, want a line from %v to %v", from, to, points, from, to)
+	}
+	for i := 1; i < len(points); i++ {
+		dx, dy := abs(points[i].X-points[i-1].X), abs(points[i].Y-points[i-1].Y)
+		if dx > 1 || dy > 1 || dx+dy == 0 {
+			t.Fatalf("bresenham(%v, %v) jumps from %v to %v", from, to, points[i-1], points[i])
+		}
+	}
+	back := bresenham(to, from)
+	if len(back) != len(points) {
+		t.Fatalf("bresenham(%v, %v) has %d points, the reverse %d", from, to, len(points), len(back))
+	}
+	for i := range points {
+		if points[i] != back[len(back)-1-i] {
+			t.Fatalf("bresenham(%v, %v) = %v is not the reverse of %v", from, to, points, back)
+		}
+	}
+}
+
+func TestBresenham(t *testing.T) {
+	tests := []struct {
+		from, to Position
+	}{
+		{Position{0, 0}, Position{0, 0}},
+		{Position{0, 0}, Position{5, 0}},
+		{Position{0, 0}, Position{0, -5}},
+		{Position{0, 0}, Position{5, 5}},
+		{Position{0, 0}, Position{7, 3}},
+		{Position{0, 0}, Position{3, 7}},
+		{Position{2, 9}, Position{-4, -1}},
+		{Position{-3, 4}, Position{8, -2}},
+	}
+	for _, test := range tests {
+		checkLine(t, test.from, test.to)
+	}
+}
+
+func FuzzBresenham(f *testing.F) {
+	f.Add(int8(0), int8(0), int8(7), int8(3))
+	f.Add(int8(-5), int8(12), int8(3), int8(-9))
+	f.Fuzz(func(t *testing.T, x1, y1, x2, y2 int8) {
+		checkLine(t, Position{int(x1), int(y1)}, Position{int(x2), int(y2)})
+	})
+}
+
+func TestCheckVisibility(t *testing.T) {
+	level := levelFromASCII(t,
+		"#############",
+		"#.....#.....#",
+		"#.....|.....#",
+		"#.....#.....#",
+		"#############",
+	)
+	player := &Character{Pos: Position{2, 2}, SightRadius: 6}
+	checkVisibility(level, player)
+
+	tests := []struct {
+		name    string
+		pos     Position
+		visible bool
+	}{
+		{"own tile", Position{2, 2}, true},
+		{"same room", Position{5, 1}, true},
+		{"wall of the room", Position{0, 0}, true},
+		{"closed door", Position{6, 2}, true},
+		{"behind the door", Position{8, 2}, false},
+		{"other room", Position{10, 3}, false},
+	}
+	for _, test := range tests {
+		if got := level.IsVisible(test.pos); got != test.visible {
+			t.Errorf("%s: visible at %v = %v, want %v", test.name, test.pos, got, test.visible)
+		}
+		if test.visible && !level.IsVisited(test.pos) {
+			t.Errorf("%s: %v visible but not visited", test.name, test.pos)
+		}
+	}
+
+	checkDoor(Position{6, 2}, level)
+	checkVisibility(level, player)
+	if !level.IsVisible(Position{8, 2}) {
+		t.Error("tile behind the open door is not visible")
+	}
+
+	player.Pos = Position{10, 2}
+	player.SightRadius = 1
+	checkVisibility(level, player)
+	if level.IsVisible(Position{2, 2}) {
+		t.Error("visibility from the old position was kept")
+	}
+	if !level.IsVisited(Position{2, 2}) {
+		t.Error("visited tiles were forgotten")
+	}
+	if level.IsVisible(Position{7, 2}) {
+		t.Error("tile beyond the sight radius is visible")
+	}
+}
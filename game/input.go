@@ -21,6 +21,35 @@ const (
 	Quit
 )
 
+var inputNames = map[InputType]string{
+	None:    "none",
+	Up:      "up",
+	Down:    "down",
+	Left:    "left",
+	Right:   "right",
+	Action:  "action",
+	Search:  "search",
+	ZoomIn:  "zoom_in",
+	ZoomOut: "zoom_out",
+	Quit:    "quit",
+}
+
+func (t InputType) String() string {
+	if name, ok := inputNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("InputType(%d)", int(t))
+}
+
+func parseInputType(s string) (InputType, bool) {
+	for t, name := range inputNames {
+		if name == s {
+			return t, true
+		}
+	}
+	return None, false
+}
+
 func handleInput(level *Level, input *Input) {
 	toPos := level.Player.Pos
 	switch input.Type {
@@ -37,20 +66,30 @@ func handleInput(level *Level, input *Input) {
 		for _, n := range ns {
 			checkDoor(n, level)
 		}
+		pullSwitches(level, level.Player.Pos)
+	case Search:
+		search(level)
+	}
+	if toPos == level.Player.Pos {
+		return
 	}
 	if canMove(toPos, level) {
 		level.Player.Move(toPos, level)
+		pickUpKey(level)
+		springTrap(level)
 	} else {
 		exists, e := hasEnemy(toPos, level)
 		if exists {
 			damageAmount := level.Player.Level * 5
-			fmt.Println(level.Player.Name, "attacked", e.Name, "for", damageAmount, "damage!")
+			fmt.Fprintln(Log, level.Player.Name, "attacked", e.Name, "for", damageAmount, "damage!")
 			e.Health -= int(damageAmount)
+			level.addAttack(&level.Player.Character, &e.Character, int(damageAmount))
 			if e.Health <= 0 {
-				fmt.Println(e.Name, "is dead.")
+				fmt.Fprintln(Log, e.Name, "is dead.")
 				e.IsDead = true
 			}
 		}
+		unlockDoor(level, toPos)
 		checkDoor(toPos, level)
 	}
 
@@ -3,256 +3,453 @@ package game
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"math"
 	"math/rand"
 	"os"
 	"strconv"
 	"strings"
-	"time"
 )
 
 type Level struct {
-	Map     [][]Tile
-	Visible [][]bool
-	Visited [][]bool
 	Player  *Player
 	Enemies []*Enemy
 	Width   int
 	Height  int
 	Debug   map[Position]bool
+	Turn    int
+	Attacks []Attack
+	Lights  []*LightSource
+	// exposure is the light on the player's tile from everything but the
+	// player's own torch. Enemies notice the player by it.
+	exposure Light
+
+	// tiles, visible, visited and light hold one entry per tile, row by
+	// row. Use Tile, IsVisible, IsVisited and LightAt to read them.
+	tiles   []Tile
+	visible []bool
+	visited []bool
+	light   []Light
+	// changed lists the tiles whose tile or visited flag changed since the
+	// last TakeChanged. allChanged replaces it once it grows past the
+	// number of tiles, so it stays bounded when nobody takes it.
+	changed    []Position
+	allChanged bool
+
+	// rng is the only source of randomness in a game, so a seed replays it.
+	rng *rand.Rand
+	// reserved holds the tiles enemies have moved into or picked to attack
+	// from this turn, so no two of them go for the same one.
+	reserved map[Position]*Enemy
+	// occupants is the enemy on each tile, row by row. See occupancy.go.
+	occupants []*Enemy
+
+	PlayerSpawn *Position
+	EnemySpawns []Position
+	// SpawnZone lists the floor tiles enemies without a spawn point are
+	// placed on. When empty they can go anywhere.
+	SpawnZone []Position
+
+	// Keys lie on the floor until the player walks over them. Locks names
+	// the key each locked door needs, "" for doors any unnamed key opens.
+	Keys     map[Position]string
+	Locks    map[Position]string
+	Switches []*Switch
+
+	Traps []*Trap
+	// SecretDoors look like walls until the player finds them.
+	SecretDoors map[Position]bool
+}
+
+// Attack records one hit during the current turn so that a UI can animate
+// it without knowing about the combat rules.
+type Attack struct {
+	Attacker *Character
+	Defender *Character
+	Damage   int
+}
+
+func (level *Level) addAttack(attacker, defender *Character, damage int) {
+	level.Attacks = append(level.Attacks, Attack{attacker, defender, damage})
+}
+
+func NewLevel(width, height int) *Level {
+	level := &Level{}
+	level.rng = rand.New(rand.NewSource(1))
+	level.Width = width
+	level.Height = height
+	level.tiles = make([]Tile, width*height)
+	level.visible = make([]bool, width*height)
+	level.visited = make([]bool, width*height)
+	level.allChanged = true
+	level.light = make([]Light, width*height)
+	level.occupants = make([]*Enemy, width*height)
+	level.Keys = make(map[Position]string)
+	level.Locks = make(map[Position]string)
+	level.SecretDoors = make(map[Position]bool)
+	return level
+}
+
+// index is the offset of pos in the tile slices and the occupancy grid.
+func (level *Level) index(pos Position) int {
+	return pos.Y*level.Width + pos.X
+}
+
+// Tile returns the tile at pos, or a blank one outside the level.
+func (level *Level) Tile(pos Position) Tile {
+	if !level.inBounds(pos) {
+		return Tile{}
+	}
+	return level.tiles[level.index(pos)]
 }
 
-func LoadLevelFromCSVFile(filename string) *Level {
+// SetTile replaces the tile at pos. Positions outside the level are
+// ignored.
+func (level *Level) SetTile(pos Position, tile Tile) {
+	if level.inBounds(pos) && level.tiles[level.index(pos)] != tile {
+		level.tiles[level.index(pos)] = tile
+		level.markChanged(pos)
+	}
+}
+
+// setTileType changes the type of the tile at pos and keeps its lock.
+func (level *Level) setTileType(pos Position, t TileType) {
+	tile := level.Tile(pos)
+	tile.TileType = t
+	level.SetTile(pos, tile)
+}
+
+func (level *Level) setLocked(pos Position, locked bool) {
+	tile := level.Tile(pos)
+	tile.Locked = locked
+	level.SetTile(pos, tile)
+}
+
+func (level *Level) setVisited(pos Position, visited bool) {
+	if level.visited[level.index(pos)] != visited {
+		level.visited[level.index(pos)] = visited
+		level.markChanged(pos)
+	}
+}
+
+func (level *Level) markChanged(pos Position) {
+	switch {
+	case level.allChanged:
+	case len(level.changed) >= len(level.tiles):
+		level.changed = nil
+		level.allChanged = true
+	default:
+		level.changed = append(level.changed, pos)
+	}
+}
+
+// TakeChanged returns the tiles whose tile or visited flag changed since
+// the last call, or true when every tile has to be treated as changed.
+func (level *Level) TakeChanged() ([]Position, bool) {
+	changed, all := level.changed, level.allChanged
+	level.changed = nil
+	level.allChanged = false
+	return changed, all
+}
+
+// IsVisible reports whether the player can see pos this turn.
+func (level *Level) IsVisible(pos Position) bool {
+	return level.inBounds(pos) && level.visible[level.index(pos)]
+}
+
+// IsVisited reports whether the player has ever seen pos.
+func (level *Level) IsVisited(pos Position) bool {
+	return level.inBounds(pos) && level.visited[level.index(pos)]
+}
+
+// csvTiles maps the tile ids of the Tiled CSV export to tiles.
+var csvTiles = map[int]TileType{
+	-2:  Blank,
+	-1:  Floor,
+	8:   Hole,
+	42:  WallS,
+	64:  WallSW,
+	65:  Wall,
+	66:  WallSE,
+	67:  WallSW,
+	68:  WallNWE,
+	69:  WallNSE,
+	71:  ClosedDoorH,
+	73:  WallE,
+	74:  WallSWE,
+	75:  WallW,
+	96:  WallNS,
+	98:  WallNS,
+	102: ClosedDoorV,
+	106: WallN,
+	128: WallNW,
+	129: Wall,
+	130: WallNE,
+	224: ClosedChest,
+	226: OpenChest,
+}
+
+func LoadLevelFromCSVFile(filename string) (*Level, error) {
 	file, err := os.Open(filename)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	defer file.Close()
+	return loadLevelFromCSV(file, filename)
+}
 
-	scanner := bufio.NewScanner(file)
+// loadLevelFromCSV reads one row of comma separated tile ids per line.
+// Errors are reported as name:line:cell.
+func loadLevelFromCSV(r io.Reader, name string) (*Level, error) {
+	scanner := bufio.NewScanner(r)
 
-	levelLines := make([]string, 0)
-	cols := 0
-	rows := 0
+	rows := make([][]string, 0)
 	for scanner.Scan() {
-		line := scanner.Text()
-		l := strings.Split(line, ",")
-		if len(l) > cols {
-			cols = len(l)
+		cells := strings.Split(scanner.Text(), ",")
+		if len(rows) > 0 && len(cells) != len(rows[0]) {
+			return nil, fmt.Errorf("%s:%d: %d cells, expected %d like line 1", name, len(rows)+1, len(cells), len(rows[0]))
 		}
-		levelLines = append(levelLines, line)
-		rows++
+		rows = append(rows, cells)
 	}
-	level := &Level{}
-	level.Width = cols
-	level.Height = rows
-	level.Map = make([][]Tile, rows)
-	level.Visible = make([][]bool, rows)
-	level.Visited = make([][]bool, rows)
-	for i := range level.Map {
-		level.Map[i] = make([]Tile, cols)
-		level.Visible[i] = make([]bool, cols)
-		level.Visited[i] = make([]bool, cols)
-	}
-	for y := 0; y < rows; y++ {
-		line := strings.Split(levelLines[y], ",")
-		for x := 0; x < len(line); x++ {
-			c, _ := strconv.Atoi(line[x])
-			var t Tile
-			switch c {
-			case -2:
-				t.TileType = Blank
-			case 8:
-				t.TileType = Hole
-			case 42:
-				t.TileType = WallS
-			case 65, 129:
-				t.TileType = Wall
-			case 64:
-				t.TileType = WallSW
-			case 67:
-				t.TileType = WallSW
-			case 66:
-				t.TileType = WallSE
-			case 68:
-				t.TileType = WallNWE
-			case 69:
-				t.TileType = WallNSE
-			case 73:
-				t.TileType = WallE
-			case 74:
-				t.TileType = WallSWE
-			case 75:
-				t.TileType = WallW
-			case 96, 98:
-				t.TileType = WallNS
-			case 106:
-				t.TileType = WallN
-			case 128:
-				t.TileType = WallNW
-			case 130:
-				t.TileType = WallNE
-			case -1:
-				t.TileType = Floor
-			case 71:
-				t.TileType = ClosedDoorH
-			case 102:
-				t.TileType = ClosedDoorV
-			case 224:
-				t.TileType = ClosedChest
-			case 226:
-				t.TileType = OpenChest
-			default:
-				t.TileType = Blank
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s: no tiles", name)
+	}
+	level := NewLevel(len(rows[0]), len(rows))
+	for y, cells := range rows {
+		for x, cell := range cells {
+			id, err := strconv.Atoi(strings.TrimSpace(cell))
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d:%d: bad tile id %q", name, y+1, x+1, cell)
 			}
-			level.Map[y][x] = t
+			t, ok := csvTiles[id]
+			if !ok {
+				return nil, fmt.Errorf("%s:%d:%d: unknown tile id %d", name, y+1, x+1, id)
+			}
+			level.setTileType(Position{x, y}, t)
 		}
 	}
-	//checkLevelWallOrientation(level)
-	//checkLevelDoors(level)
-	return level
+	Autotile(level)
+	addMapLights(level)
+	return level, nil
 }
 
-func (level *Level) resetVisibility(v bool) {
-	for y := 0; y < level.Height; y++ {
-		for x := 0; x < level.Width; x++ {
-			level.Visible[y][x] = v
-		}
+const (
+	playerSpawnRune = '@'
+	enemySpawnRune  = 'E'
+	spawnZoneRune   = 'z'
+	lockedDoorRune  = '='
+)
+
+// asciiLegend maps the characters of hand-drawn .map files to tiles. Walls
+// and doors only need one character each, Autotile picks their sprites.
+// Spawn points are marked with '@' for the player and 'E' for enemies and
+// stand on floor, as do the 'z' tiles of the enemy spawn zone, keys 'k' and
+// switches '!' and the hidden traps in trapRunes. '=' is a locked door and
+// '+' a secret door.
+var asciiLegend = map[rune]TileType{
+	' ': Blank,
+	'.': Floor,
+	'#': Wall,
+	'|': ClosedDoorV,
+	'/': OpenDoorV,
+	'O': Hole,
+	'*': Brazier,
+	'C': ClosedChest,
+	'c': OpenChest,
+}
+
+func LoadLevelFromASCIIFile(filename string) (*Level, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
 	}
+	defer file.Close()
+	return loadLevelFromASCII(file, filename)
 }
 
-func checkLevelDoorOrientation(level *Level) {
-	for y, rows := range level.Map {
-		for x, _ := range rows {
-			if isDoor(level, Position{x, y}) {
-				_, flags := getNeighbors(level, Position{x, y})
-				if flags == 12 {
-					level.Map[y][x].TileType = ClosedDoorH
-				} else if flags == 11 {
-					level.Map[y][x].TileType = ClosedDoorH
+// loadLevelFromASCII reads the .map format. Rows may be shorter than the
+// widest one, the rest of the row is blank. Errors are reported as
+// name:line:column.
+func loadLevelFromASCII(r io.Reader, name string) (*Level, error) {
+	scanner := bufio.NewScanner(r)
+
+	levelLines := make([][]rune, 0)
+	var wiring []string
+	cols := 0
+	for scanner.Scan() {
+		if wiring != nil || scanner.Text() == wiringSeparator {
+			wiring = append(wiring, scanner.Text())
+			continue
+		}
+		line := []rune(scanner.Text())
+		if len(line) > cols {
+			cols = len(line)
+		}
+		levelLines = append(levelLines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if cols == 0 {
+		return nil, fmt.Errorf("%s: no tiles", name)
+	}
+	level := NewLevel(cols, len(levelLines))
+	for y, line := range levelLines {
+		for x, c := range line {
+			t, ok := asciiLegend[c]
+			trapType, isTrap := trapRunes[c]
+			switch {
+			case c == playerSpawnRune:
+				if level.PlayerSpawn != nil {
+					return nil, fmt.Errorf("%s:%d:%d: second player spawn, the first is at %d:%d", name, y+1, x+1, level.PlayerSpawn.Y+1, level.PlayerSpawn.X+1)
 				}
+				t = Floor
+				level.PlayerSpawn = &Position{x, y}
+			case c == enemySpawnRune:
+				t = Floor
+				level.EnemySpawns = append(level.EnemySpawns, Position{x, y})
+			case c == spawnZoneRune:
+				t = Floor
+				level.SpawnZone = append(level.SpawnZone, Position{x, y})
+			case c == lockedDoorRune:
+				t = ClosedDoorV
+				level.setLocked(Position{x, y}, true)
+			case c == keyRune:
+				t = Floor
+				level.Keys[Position{x, y}] = ""
+			case c == switchRune:
+				t = Floor
+				level.Switches = append(level.Switches, &Switch{Pos: Position{x, y}})
+			case c == secretDoorRune:
+				t = Wall
+				level.SecretDoors[Position{x, y}] = true
+			case isTrap:
+				t = Floor
+				level.Traps = append(level.Traps, &Trap{Pos: Position{x, y}, Type: trapType})
+			case !ok:
+				return nil, fmt.Errorf("%s:%d:%d: unknown tile %q", name, y+1, x+1, c)
 			}
+			level.setTileType(Position{x, y}, t)
+		}
+	}
+	if wiring != nil {
+		if err := parseWiring(level, wiring[1:], len(levelLines)+2, name); err != nil {
+			return nil, err
 		}
 	}
+	Autotile(level)
+	addMapLights(level)
+	return level, nil
 }
 
-func checkLevelWallOrientation(level *Level) {
-	for y, rows := range level.Map {
-		for x, _ := range rows {
-			if isWall(level, Position{x, y}) {
-				flags := getWallNeighbors(level, Position{x, y})
-				if flags == 10 {
-					level.Map[y][x].TileType = WallSW
-				} else if flags == 12 {
-					level.Map[y][x].TileType = WallNS
-				} else if flags == 6 {
-					level.Map[y][x].TileType = WallNW
-				} else if flags == 5 {
-					level.Map[y][x].TileType = WallNE
-				} else if flags == 9 {
-					level.Map[y][x].TileType = WallSE
-				} else if flags == 4 {
-					level.Map[y][x].TileType = WallN
-				} else if flags == 8 {
-					level.Map[y][x].TileType = WallS
-				} else if flags == 11 {
-					level.Map[y][x].TileType = WallSWE
-				} else if flags == 15 {
-					level.Map[y][x].TileType = WallSWE
-				} else if flags == 14 {
-					level.Map[y][x].TileType = WallNSW
-				} else if flags == 13 {
-					level.Map[y][x].TileType = WallNSE
-				}
-
-			}
+func asciiRune(level *Level, pos Position) rune {
+	if level.SecretDoors[pos] {
+		return secretDoorRune
+	}
+	if trap := level.trapAt(pos); trap != nil {
+		return trapRune(trap.Type)
+	}
+	if level.PlayerSpawn != nil && *level.PlayerSpawn == pos {
+		return playerSpawnRune
+	}
+	for _, p := range level.EnemySpawns {
+		if p == pos {
+			return enemySpawnRune
 		}
 	}
+	for _, p := range level.SpawnZone {
+		if p == pos {
+			return spawnZoneRune
+		}
+	}
+	if c, ok := level.ObjectRune(pos); ok {
+		return c
+	}
+	return level.TileRune(pos)
 }
 
-func getWallNeighbors(level *Level, pos Position) uint8 {
-	var flags uint8
-	left := Position{pos.X - 1, pos.Y}
-	right := Position{pos.X + 1, pos.Y}
-	up := Position{pos.X, pos.Y - 1}
-	down := Position{pos.X, pos.Y + 1}
-
-	if left.X >= 0 && left.X < level.Width && isWall(level, left) {
-		flags |= 1 << 0
-	}
-	if right.X >= 0 && right.X < level.Width && isWall(level, right) {
-		flags |= 1 << 1
+// TileRune returns the .map character for the tile at pos, ignoring spawn
+// points.
+func (level *Level) TileRune(pos Position) rune {
+	switch {
+	case isWall(level, pos):
+		return '#'
+	case isClosedDoor(level, pos) && level.Tile(pos).Locked:
+		return lockedDoorRune
+	case isClosedDoor(level, pos):
+		return '|'
+	case isDoor(level, pos):
+		return '/'
 	}
-	if up.Y >= 0 && up.Y < level.Height && isWall(level, up) {
-		flags |= 1 << 2
+	t := level.getTileType(pos)
+	for c, legendType := range asciiLegend {
+		if legendType == t {
+			return c
+		}
 	}
-	if down.Y >= 0 && down.Y < level.Height && isWall(level, down) {
-		flags |= 1 << 3
+	return ' '
+}
+
+// SaveLevelToASCIIFile writes the level in the format read by
+// LoadLevelFromASCIIFile. Trailing blank tiles are trimmed from each row.
+func SaveLevelToASCIIFile(level *Level, filename string) error {
+	var b strings.Builder
+	for y := 0; y < level.Height; y++ {
+		line := make([]rune, level.Width)
+		for x := range line {
+			line[x] = asciiRune(level, Position{x, y})
+		}
+		b.WriteString(strings.TrimRight(string(line), " "))
+		b.WriteByte('\n')
 	}
+	writeWiring(&b, level)
+	return os.WriteFile(filename, []byte(b.String()), 0644)
+}
 
-	return flags
+func (level *Level) resetVisibility(v bool) {
+	for i := range level.visible {
+		level.visible[i] = v
+	}
 }
 
 func isWall(level *Level, pos Position) bool {
 	if pos.X < 0 || pos.X > level.Width-1 {
-		//fmt.Println("X position out of level bounds!", "pos", pos, "width", level.Width)
+		//fmt.Fprintln(Log, "X position out of level bounds!", "pos", pos, "width", level.Width)
 		return true
 	}
 	if pos.Y < 0 || pos.Y > level.Height-1 {
-		//fmt.Println("Y position out of level bounds!", "pos", pos, "height", level.Height)
-		return true
-	}
-	if level.Map[pos.Y][pos.X].TileType == Wall ||
-		level.Map[pos.Y][pos.X].TileType == WallN ||
-		level.Map[pos.Y][pos.X].TileType == WallW ||
-		level.Map[pos.Y][pos.X].TileType == WallS ||
-		level.Map[pos.Y][pos.X].TileType == WallE ||
-		level.Map[pos.Y][pos.X].TileType == WallNS ||
-		level.Map[pos.Y][pos.X].TileType == WallNE ||
-		level.Map[pos.Y][pos.X].TileType == WallNW ||
-		level.Map[pos.Y][pos.X].TileType == WallSW ||
-		level.Map[pos.Y][pos.X].TileType == WallSE ||
-		level.Map[pos.Y][pos.X].TileType == WallSWE ||
-		level.Map[pos.Y][pos.X].TileType == WallNSE ||
-		level.Map[pos.Y][pos.X].TileType == WallNSW ||
-		level.Map[pos.Y][pos.X].TileType == WallNWE {
+		//fmt.Fprintln(Log, "Y position out of level bounds!", "pos", pos, "height", level.Height)
 		return true
 	}
-	return false
+	t := level.tiles[level.index(pos)].TileType
+	return t >= Wall && t <= WallFill
 }
 
 func isDoor(level *Level, pos Position) bool {
-	if level.Map[pos.Y][pos.X].TileType == ClosedDoorV ||
-		level.Map[pos.Y][pos.X].TileType == OpenDoorV ||
-		level.Map[pos.Y][pos.X].TileType == ClosedDoorH ||
-		level.Map[pos.Y][pos.X].TileType == OpenDoorH {
-		return true
-	}
-	return false
+	t := level.getTileType(pos)
+	return t >= ClosedDoorV && t <= OpenDoorH
 }
 
 func isClosedDoor(level *Level, pos Position) bool {
-	if level.Map[pos.Y][pos.X].TileType == ClosedDoorV ||
-		level.Map[pos.Y][pos.X].TileType == ClosedDoorH {
-		return true
-	}
-	return false
+	t := level.getTileType(pos)
+	return t == ClosedDoorV || t == ClosedDoorH
+}
+
+// canOpen reports whether a character could open the door at pos.
+func canOpen(level *Level, pos Position) bool {
+	return level.inBounds(pos) && isClosedDoor(level, pos) && !level.Tile(pos).Locked
 }
 
 func hasEnemy(pos Position, level *Level) (bool, *Enemy) {
-	for _, e := range level.Enemies {
-		if pos == e.Pos {
-			return true, e
-		}
-	}
-	return false, nil
+	e := level.EnemyAt(pos)
+	return e != nil, e
 }
 
 func canMove(pos Position, level *Level) bool {
-	if isWall(level, pos) ||
-		level.Map[pos.Y][pos.X].TileType == ClosedDoorV ||
-		level.Map[pos.Y][pos.X].TileType == ClosedDoorH ||
-		level.Map[pos.Y][pos.X].TileType == ClosedChest {
+	if !isOpenTile(level, pos) {
 		return false
 	}
 	exists, _ := hasEnemy(pos, level)
@@ -262,24 +459,58 @@ func canMove(pos Position, level *Level) bool {
 	return true
 }
 
+// isOpenTile reports whether the tile at pos can be walked on, whoever is
+// standing there now.
+func isOpenTile(level *Level, pos Position) bool {
+	if !level.inBounds(pos) || isWall(level, pos) || isBlank(level, pos) {
+		return false
+	}
+	t := level.getTileType(pos)
+	return t != ClosedDoorV && t != ClosedDoorH && t != ClosedChest && t != Brazier
+}
+
+// IsPassable reports whether a character could ever stand on pos, counting
+// closed and secret doors as passable since they can be opened.
+func (level *Level) IsPassable(pos Position) bool {
+	if level.SecretDoors[pos] {
+		return true
+	}
+	if !level.inBounds(pos) || isWall(level, pos) || isBlank(level, pos) {
+		return false
+	}
+	t := level.getTileType(pos)
+	return t != ClosedChest && t != Brazier
+}
+
 func checkDoor(pos Position, level *Level) {
-	if level.Map[pos.Y][pos.X].TileType == ClosedDoorV {
-		level.Map[pos.Y][pos.X].TileType = OpenDoorV
-	} else if level.Map[pos.Y][pos.X].TileType == OpenDoorV {
-		level.Map[pos.Y][pos.X].TileType = ClosedDoorV
+	if !level.inBounds(pos) {
+		return
 	}
-	if level.Map[pos.Y][pos.X].TileType == ClosedDoorH {
-		level.Map[pos.Y][pos.X].TileType = OpenDoorH
-	} else if level.Map[pos.Y][pos.X].TileType == OpenDoorH {
-		level.Map[pos.Y][pos.X].TileType = ClosedDoorH
+	if level.Tile(pos).Locked {
+		fmt.Fprintln(Log, "the door is locked.")
+		return
 	}
+	toggleDoor(pos, level)
+}
 
+// toggleDoor opens a closed door or closes an open one, locked or not.
+func toggleDoor(pos Position, level *Level) {
+	switch level.getTileType(pos) {
+	case ClosedDoorV:
+		level.setTileType(pos, OpenDoorV)
+	case OpenDoorV:
+		level.setTileType(pos, ClosedDoorV)
+	case ClosedDoorH:
+		level.setTileType(pos, OpenDoorH)
+	case OpenDoorH:
+		level.setTileType(pos, ClosedDoorH)
+	}
 }
 
 func checkHole(pos Position, level *Level) {
 	t := level.getTileType(pos)
 	if t == Hole {
-		fmt.Println("you check the hole")
+		fmt.Fprintln(Log, "you check the hole")
 	}
 }
 
@@ -311,20 +542,22 @@ func getNeighbors(level *Level, pos Position) ([]Position, uint8) {
 	return neighbors, flags
 }
 
-func getRandomPositionInsideCircle(radius int, pos Position) Position {
+// SetSeed restarts the level's random number generator from seed.
+func (level *Level) SetSeed(seed int64) {
+	level.rng = rand.New(rand.NewSource(seed))
+}
+
+func getRandomPositionInsideCircle(rng *rand.Rand, radius int, pos Position) Position {
 	var p Position
-	angle := 2.0 * math.Pi * rand.Float64()
-	r := float64(radius) * math.Sqrt(rand.Float64())
+	angle := 2.0 * math.Pi * rng.Float64()
+	r := float64(radius) * math.Sqrt(rng.Float64())
 	p.X = int(r*math.Cos(angle) + float64(pos.X))
 	p.Y = int(r*math.Sin(angle) + float64(pos.Y))
 	return p
 }
 
 func isBlank(level *Level, pos Position) bool {
-	if level.Map[pos.Y][pos.X].TileType == Blank {
-		return true
-	}
-	return false
+	return level.getTileType(pos) == Blank
 }
 
 func isSolid(level *Level, pos Position) bool {
@@ -334,22 +567,34 @@ func isSolid(level *Level, pos Position) bool {
 	return false
 }
 
+func (level *Level) inBounds(pos Position) bool {
+	return pos.X >= 0 && pos.X < level.Width && pos.Y >= 0 && pos.Y < level.Height
+}
+
 func (level *Level) getTileType(pos Position) TileType {
-	return level.Map[pos.Y][pos.X].TileType
+	return level.Tile(pos).TileType
 }
 
-func (level *Level) getRandomPosition() Position {
-	t := time.Now().Nanosecond()
-	rand.Seed(int64(t))
-	pos := Position{-1, -1}
-	for pos.X < 0 || pos.Y < 0 || isWall(level, pos) || isBlank(level, pos) {
-		e, _ := hasEnemy(pos, level)
-		if e {
-			pos = Position{-1, -1}
-			continue
+// isFreeFloor reports whether a character can be placed on pos: plain
+// floor, not a door, hole or chest, with nobody standing on it.
+func (level *Level) isFreeFloor(pos Position) bool {
+	return level.inBounds(pos) && level.getTileType(pos) == Floor && canMove(pos, level) &&
+		(level.Player == nil || level.Player.Pos != pos)
+}
+
+// getRandomPosition picks a free floor tile.
+func (level *Level) getRandomPosition() (Position, error) {
+	free := make([]Position, 0)
+	for y := 0; y < level.Height; y++ {
+		for x := 0; x < level.Width; x++ {
+			pos := Position{x, y}
+			if level.isFreeFloor(pos) {
+				free = append(free, pos)
+			}
 		}
-		pos.X = rand.Intn(level.Width - 1)
-		pos.Y = rand.Intn(level.Height - 1)
 	}
-	return pos
+	if len(free) == 0 {
+		return Position{}, fmt.Errorf("no free floor left to place a character on")
+	}
+	return free[level.rng.Intn(len(free))], nil
 }
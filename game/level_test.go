@@ -0,0 +1,437 @@
+package game
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func levelFromASCII(t *testing.T, rows ...string) *Level {
+	t.Helper()
+	level, err := loadLevelFromASCII(strings.NewReader(strings.Join(rows, "\n")), "test.map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return level
+}
+
+func writeTemp(t *testing.T, name, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoadLevelFromCSVFile(t *testing.T) {
+	tests := []struct {
+		name          string
+		csv           string
+		width, height int
+		tiles         map[Position]TileType
+		err           string
+	}{
+		{
+			name: "empty",
+			csv:  "",
+			err:  "level.csv: no tiles",
+		},
+		{
+			name:  "tiles",
+			csv:   "-1,-1,-2\n -1 ,8,224\n",
+			width: 3, height: 2,
+			tiles: map[Position]TileType{
+				{0, 0}: Floor, {1, 0}: Floor, {2, 0}: Blank,
+				{0, 1}: Floor, {1, 1}: Hole, {2, 1}: ClosedChest,
+			},
+		},
+		{
+			name: "ragged rows",
+			csv:  "-1,-1,-1\n-1,-1,-1\n-1\n",
+			err:  "level.csv:3: 1 cells, expected 3 like line 1",
+		},
+		{
+			name: "bad id",
+			csv:  "-1,-1\n-1,abc\n",
+			err:  `level.csv:2:2: bad tile id "abc"`,
+		},
+		{
+			name: "empty cell",
+			csv:  "-1,,-1\n",
+			err:  `level.csv:1:2: bad tile id ""`,
+		},
+		{
+			name: "unknown id",
+			csv:  "-1,9999\n",
+			err:  "level.csv:1:2: unknown tile id 9999",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filename := writeTemp(t, "level.csv", test.csv)
+			level, err := LoadLevelFromCSVFile(filename)
+			if test.err != "" {
+				want := filepath.Join(filepath.Dir(filename), test.err)
+				if err == nil || err.Error() != want {
+					t.Fatalf("error = %v, want %s", err, want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if level.Width != test.width || level.Height != test.height {
+				t.Fatalf("size = %dx%d, want %dx%d", level.Width, level.Height, test.width, test.height)
+			}
+			for pos, want := range test.tiles {
+				if got := level.getTileType(pos); got != want {
+					t.Errorf("tile at %v = %s, want %s", pos, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadLevelFromASCIIErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		ascii string
+		err   string
+	}{
+		{"empty", "", "test.map: no tiles"},
+		{"blank lines", "\n\n", "test.map: no tiles"},
+		{"unknown tile", "###\n#x#\n###\n", `test.map:2:2: unknown tile 'x'`},
+		{"two player spawns", "#####\n#@.@#\n#####\n", "test.map:2:4: second player spawn, the first is at 2:2"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := loadLevelFromASCII(strings.NewReader(test.ascii), "test.map")
+			if err == nil || err.Error() != test.err {
+				t.Errorf("error = %v, want %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestLoadLevelMissingFile(t *testing.T) {
+	for _, name := range []string{"missing.map", "missing.csv"} {
+		if _, err := LoadLevel(filepath.Join(t.TempDir(), name)); err == nil {
+			t.Errorf("LoadLevel(%s) did not fail", name)
+		}
+	}
+}
+
+func TestLoadLevelFromASCIISpawns(t *testing.T) {
+	level := levelFromASCII(t,
+		"#####",
+		"#@.E#",
+		"#E..#",
+		"#####",
+	)
+	if level.PlayerSpawn == nil || *level.PlayerSpawn != (Position{1, 1}) {
+		t.Errorf("player spawn = %v, want {1 1}", level.PlayerSpawn)
+	}
+	want := []Position{{3, 1}, {1, 2}}
+	if len(level.EnemySpawns) != len(want) {
+		t.Fatalf("enemy spawns = %v, want %v", level.EnemySpawns, want)
+	}
+	for i, pos := range want {
+		if level.EnemySpawns[i] != pos {
+			t.Errorf("enemy spawn %d = %v, want %v", i, level.EnemySpawns[i], pos)
+		}
+		if level.getTileType(pos) != Floor {
+			t.Errorf("tile under enemy spawn %v = %s, want floor", pos, level.getTileType(pos))
+		}
+	}
+}
+
+func TestSaveLevelToASCIIFileRoundTrip(t *testing.T) {
+	rows := []string{
+		"#######",
+		"#@..*.#",
+		"#..E..|",
+		"#.C.O.#",
+		"###/#=#",
+	}
+	filename := filepath.Join(t.TempDir(), "level.map")
+	if err := SaveLevelToASCIIFile(levelFromASCII(t, rows...), filename); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), strings.Join(rows, "\n")+"\n"; got != want {
+		t.Errorf("saved level:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCanMove(t *testing.T) {
+	level := levelFromASCII(t,
+		"#####",
+		"#..*#",
+		"#.C.|",
+		"#.. #",
+		"#####",
+	)
+	level.AddEnemy(NewEnemy("enemy", 1, Position{1, 3}))
+	tests := []struct {
+		pos  Position
+		want bool
+	}{
+		{Position{-1, 1}, false},
+		{Position{1, -1}, false},
+		{Position{level.Width, 1}, false},
+		{Position{1, level.Height}, false},
+		{Position{1, 1}, true},
+		{Position{0, 0}, false},
+		{Position{3, 1}, false},
+		{Position{2, 2}, false},
+		{Position{4, 2}, false},
+		{Position{3, 3}, false},
+		{Position{1, 3}, false},
+		{Position{2, 3}, true},
+	}
+	for _, test := range tests {
+		if got := canMove(test.pos, level); got != test.want {
+			t.Errorf("canMove(%v) = %v, want %v", test.pos, got, test.want)
+		}
+	}
+}
+
+func TestGetNeighbors(t *testing.T) {
+	level := levelFromASCII(t,
+		"#####",
+		"#...#",
+		"#...#",
+		"#...#",
+		"#####",
+	)
+	const (
+		left  = 1 << 0
+		right = 1 << 1
+		up    = 1 << 2
+		down  = 1 << 3
+	)
+	tests := []struct {
+		name    string
+		pos     Position
+		enemies []Position
+		flags   uint8
+	}{
+		{"centre", Position{2, 2}, nil, left | right | up | down},
+		{"top left", Position{1, 1}, nil, right | down},
+		{"bottom right", Position{3, 3}, nil, left | up},
+		{"enemy blocks", Position{2, 2}, []Position{{3, 2}, {2, 1}}, left | down},
+		{"outside", Position{-1, -1}, nil, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clearEnemies(level)
+			for _, pos := range test.enemies {
+				level.AddEnemy(NewEnemy("enemy", 1, pos))
+			}
+			ns, flags := getNeighbors(level, test.pos)
+			if flags != test.flags {
+				t.Errorf("flags = %04b, want %04b", flags, test.flags)
+			}
+			for _, n := range ns {
+				if !canMove(n, level) {
+					t.Errorf("neighbour %v is not walkable", n)
+				}
+			}
+		})
+	}
+}
+
+// goldenRunes draws every tile type as its own character so the golden
+// files also catch changes to the autotiler. Wall pieces are drawn with the
+// neighbours the autotiler gave them that sprite for.
+var goldenRunes = map[TileType]rune{
+	Blank:       ' ',
+	Wall:        '#',
+	WallEW:      '=',
+	WallSW:      '┌',
+	WallNS:      '│',
+	WallNW:      '└',
+	WallNE:      '┘',
+	WallSE:      '┐',
+	WallN:       '╵',
+	WallS:       '╷',
+	WallE:       '╶',
+	WallW:       '╴',
+	WallSWE:     '┬',
+	WallNSW:     '├',
+	WallNSE:     '┤',
+	WallNWE:     '┴',
+	WallNSWE:    '┼',
+	WallFill:    '█',
+	Floor:       '.',
+	Hole:        'O',
+	ClosedDoorV: '|',
+	OpenDoorV:   '/',
+	ClosedDoorH: '-',
+	OpenDoorH:   '\\',
+	Brazier:     '*',
+	ClosedChest: 'C',
+	OpenChest:   'c',
+}
+
+func renderGolden(level *Level) string {
+	var b strings.Builder
+	for y := 0; y < level.Height; y++ {
+		line := make([]rune, level.Width)
+		for x := range line {
+			r, ok := goldenRunes[level.getTileType(Position{x, y})]
+			if !ok {
+				r = '?'
+			}
+			line[x] = r
+		}
+		b.WriteString(strings.TrimRight(string(line), " "))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func TestLoadLevelGolden(t *testing.T) {
+	tests := []struct {
+		mapFile string
+		golden  string
+	}{
+		{"maps/level1.map", "testdata/level1.golden"},
+		{"maps/dungeon_csv_Wall.csv", "testdata/dungeon_csv_Wall.golden"},
+		{"", "testdata/dungeon_csv_Wall.golden"},
+	}
+	for _, test := range tests {
+		name := test.mapFile
+		if name == "" {
+			name = "embedded"
+		}
+		t.Run(name, func(t *testing.T) {
+			level, err := LoadLevel(test.mapFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := renderGolden(level)
+			if *update && test.mapFile != "" {
+				if err := os.WriteFile(test.golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(test.golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("%s does not match %s, run go test -update to accept the change", test.mapFile, test.golden)
+			}
+		})
+	}
+}
+
+func FuzzLoadLevelFromCSV(f *testing.F) {
+	f.Add("-1,-1,65\n65,-1\n")
+	f.Add("abc,,\n\n,224,226,102,71\n")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, csv string) {
+		level, err := loadLevelFromCSV(strings.NewReader(csv), "fuzz.csv")
+		if err != nil {
+			return
+		}
+		if len(level.tiles) != level.Width*level.Height {
+			t.Fatalf("%d tiles, want %dx%d", len(level.tiles), level.Width, level.Height)
+		}
+	})
+}
+
+func FuzzLoadLevelFromASCII(f *testing.F) {
+	f.Add("#####\n#@.E#\n#|*O#\n#####\n")
+	f.Add("x\n\n   #")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, ascii string) {
+		level, err := loadLevelFromASCII(strings.NewReader(ascii), "fuzz.map")
+		if err != nil {
+			return
+		}
+		if level.PlayerSpawn != nil && !level.inBounds(*level.PlayerSpawn) {
+			t.Fatalf("player spawn %v outside %dx%d level", *level.PlayerSpawn, level.Width, level.Height)
+		}
+		for _, pos := range level.EnemySpawns {
+			if level.getTileType(pos) != Floor {
+				t.Fatalf("enemy spawn %v on %s", pos, level.getTileType(pos))
+			}
+		}
+	})
+}
+
+var largeSizes = []int{128, 512}
+
+func BenchmarkIsWall(b *testing.B) {
+	for _, size := range largeSizes {
+		level := crowdedLevel(b, size, size, 0)
+		b.Run(fmt.Sprintf("%dx%d", size, size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				isWall(level, Position{i % size, (i / size) % size})
+			}
+		})
+	}
+}
+
+func BenchmarkAutotile(b *testing.B) {
+	for _, size := range largeSizes {
+		level := crowdedLevel(b, size, size, 0)
+		b.Run(fmt.Sprintf("%dx%d", size, size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Autotile(level)
+			}
+		})
+	}
+}
+
+func BenchmarkVisibility(b *testing.B) {
+	for _, size := range largeSizes {
+		level := crowdedLevel(b, size, size, 0)
+		player := NewPlayer("player", 1, Position{size / 2, size / 2})
+		player.SightRadius = 20
+		b.Run(fmt.Sprintf("%dx%d", size, size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				checkVisibility(level, &player.Character)
+			}
+		})
+	}
+}
+
+func TestTakeChanged(t *testing.T) {
+	level := levelFromASCII(t,
+		"#####",
+		"#@|.#",
+		"#####",
+	)
+	if _, all := level.TakeChanged(); !all {
+		t.Error("new level not reported as all changed")
+	}
+	toggleDoor(Position{2, 1}, level)
+	level.setVisited(Position{3, 1}, true)
+	level.setVisited(Position{3, 1}, true)
+	level.setLocked(Position{2, 1}, false)
+	changed, all := level.TakeChanged()
+	if all || len(changed) != 2 || changed[0] != (Position{2, 1}) || changed[1] != (Position{3, 1}) {
+		t.Errorf("changed %v, %v, want the door and the newly visited tile", changed, all)
+	}
+	if changed, all := level.TakeChanged(); all || len(changed) != 0 {
+		t.Errorf("changed %v, %v after taking them, want nothing", changed, all)
+	}
+	for i := 0; i <= len(level.tiles); i++ {
+		toggleDoor(Position{2, 1}, level)
+	}
+	if changed, all := level.TakeChanged(); !all || changed != nil {
+		t.Errorf("changed %v, %v after more changes than tiles, want all", changed, all)
+	}
+}
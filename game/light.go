@@ -0,0 +1,160 @@
+package game
+
+import "math"
+
+const (
+	AmbientLight       = 0.1
+	minDetectionRange  = 2.0
+	maxDetectionRange  = 6.0
+	brazierRadius      = 6
+	torchRadius        = 8
+	enemyGlowRadius    = 3
+	enemyGlowMinLevel  = 10.0
+	lightIntensityStep = 16
+)
+
+type LightColor struct {
+	R, G, B uint8
+}
+
+var (
+	TorchColor   = LightColor{255, 214, 160}
+	BrazierColor = LightColor{255, 150, 70}
+	GlowColor    = LightColor{120, 255, 140}
+)
+
+type LightSource struct {
+	Pos       Position
+	Radius    int
+	Intensity float64
+	Color     LightColor
+}
+
+// Light is the brightness of a tile between 0 and 1 and the colour mix of
+// every source reaching it.
+type Light struct {
+	Intensity float64
+	r, g, b   float64
+}
+
+// RGB returns the tint of the tile scaled by its brightness, ready to be
+// used as a colour modulation. Brightness is quantised so that neighbouring
+// tiles lit by the same source share a colour.
+func (l Light) RGB() (uint8, uint8, uint8) {
+	if l.Intensity == 0 {
+		return 0, 0, 0
+	}
+	i := math.Round(l.Intensity*lightIntensityStep) / lightIntensityStep
+	scale := i / l.Intensity
+	return uint8(math.Min(l.r*scale, 255)), uint8(math.Min(l.g*scale, 255)), uint8(math.Min(l.b*scale, 255))
+}
+
+func (l *Light) add(amount float64, color LightColor) {
+	l.Intensity += amount
+	l.r += amount * float64(color.R)
+	l.g += amount * float64(color.G)
+	l.b += amount * float64(color.B)
+}
+
+func (l *Light) clamp() {
+	if l.Intensity <= 1 {
+		return
+	}
+	l.r /= l.Intensity
+	l.g /= l.Intensity
+	l.b /= l.Intensity
+	l.Intensity = 1
+}
+
+func (level *Level) LightAt(pos Position) Light {
+	if !level.inBounds(pos) {
+		return Light{}
+	}
+	return level.light[level.index(pos)]
+}
+
+func addMapLights(level *Level) {
+	for y := 0; y < level.Height; y++ {
+		for x := 0; x < level.Width; x++ {
+			if level.getTileType(Position{x, y}) == Brazier {
+				level.Lights = append(level.Lights, &LightSource{
+					Pos:       Position{x, y},
+					Radius:    brazierRadius,
+					Intensity: 1,
+					Color:     BrazierColor,
+				})
+			}
+		}
+	}
+}
+
+func hasLineOfSight(level *Level, from, to Position) bool {
+	ps := bresenham(from, to)
+	for _, p := range ps[:len(ps)-1] {
+		if p != from && isSolid(level, p) {
+			return false
+		}
+	}
+	return true
+}
+
+func castLight(level *Level, source LightSource) {
+	for y := source.Pos.Y - source.Radius; y <= source.Pos.Y+source.Radius; y++ {
+		for x := source.Pos.X - source.Radius; x <= source.Pos.X+source.Radius; x++ {
+			pos := Position{x, y}
+			if !level.inBounds(pos) {
+				continue
+			}
+			dx := float64(x - source.Pos.X)
+			dy := float64(y - source.Pos.Y)
+			d := math.Sqrt(dx*dx + dy*dy)
+			if d > float64(source.Radius) || !hasLineOfSight(level, source.Pos, pos) {
+				continue
+			}
+			falloff := 1 - d/float64(source.Radius+1)
+			level.light[level.index(pos)].add(source.Intensity*falloff*falloff, source.Color)
+		}
+	}
+}
+
+func characterLight(character *Character) (LightSource, bool) {
+	if character.Light == nil || character.IsDead {
+		return LightSource{}, false
+	}
+	source := *character.Light
+	source.Pos = character.Pos
+	return source, true
+}
+
+// updateLighting recomputes the light of every tile from the map's light
+// sources, glowing enemies and the player's torch.
+func updateLighting(level *Level) {
+	for i := range level.light {
+		level.light[i] = Light{}
+		level.light[i].add(AmbientLight, LightColor{255, 255, 255})
+	}
+	for _, source := range level.Lights {
+		castLight(level, *source)
+	}
+	for _, enemy := range level.Enemies {
+		if source, ok := characterLight(&enemy.Character); ok {
+			castLight(level, source)
+		}
+	}
+	// The torch lights up the player's tile fully, so it is left out of
+	// how visible the player is.
+	level.exposure = level.LightAt(level.Player.Pos)
+	level.exposure.clamp()
+	if source, ok := characterLight(&level.Player.Character); ok {
+		castLight(level, source)
+	}
+	for i := range level.light {
+		level.light[i].clamp()
+	}
+}
+
+// detectionRange is how close an enemy has to be to notice a character
+// standing in the given light.
+func detectionRange(light Light) float64 {
+	return minDetectionRange + (maxDetectionRange-minDetectionRange)*light.Intensity
+}
@@ -0,0 +1,113 @@
+package game
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCastLightFalloff(t *testing.T) {
+	level := levelFromASCII(t,
+		"#########",
+		"#.......#",
+		"#########",
+	)
+	castLight(level, LightSource{Pos: Position{1, 1}, Radius: 4, Intensity: 1, Color: TorchColor})
+	tests := []struct {
+		x    int
+		want float64
+	}{
+		{1, 1},
+		{3, 0.36},
+		{5, 0.04},
+		{6, 0},
+	}
+	for _, test := range tests {
+		if got := level.LightAt(Position{test.x, 1}).Intensity; !near(got, test.want) {
+			t.Errorf("light %d tiles from the source = %v, want %v", test.x-1, got, test.want)
+		}
+	}
+}
+
+func TestCastLightBlockedByWalls(t *testing.T) {
+	level := levelFromASCII(t,
+		"#######",
+		"#..#..#",
+		"#..|..#",
+		"#######",
+	)
+	castLight(level, LightSource{Pos: Position{1, 1}, Radius: 6, Intensity: 1, Color: TorchColor})
+	for _, pos := range []Position{{4, 1}, {5, 1}, {5, 2}} {
+		if got := level.LightAt(pos).Intensity; got != 0 {
+			t.Errorf("light behind the wall at %v = %v, want 0", pos, got)
+		}
+	}
+	if level.LightAt(Position{3, 1}).Intensity == 0 {
+		t.Error("the wall itself is not lit")
+	}
+}
+
+func TestLightClamp(t *testing.T) {
+	var l Light
+	l.add(1, LightColor{200, 100, 0})
+	l.add(1, LightColor{0, 100, 200})
+	l.clamp()
+	if l.Intensity != 1 {
+		t.Errorf("intensity %v after clamp, want 1", l.Intensity)
+	}
+	if r, g, b := l.RGB(); r != 100 || g != 100 || b != 100 {
+		t.Errorf("colour %d,%d,%d after clamp, want the mix 100,100,100", r, g, b)
+	}
+
+	level := aiLevel(t, true,
+		"#######",
+		"#*@*..#",
+		"#######",
+	)
+	for y := 0; y < level.Height; y++ {
+		for x := 0; x < level.Width; x++ {
+			if got := level.LightAt(Position{x, y}).Intensity; got > 1 {
+				t.Errorf("light at %d,%d = %v, want at most 1", x, y, got)
+			}
+		}
+	}
+}
+
+func TestDetectionRange(t *testing.T) {
+	dark := []string{
+		"#########",
+		"#.......#",
+		"#...@...#",
+		"#.......#",
+		"#########",
+	}
+	lit := []string{
+		"#########",
+		"#.......#",
+		"#..*@...#",
+		"#.......#",
+		"#########",
+	}
+	darkRange := minDetectionRange + (maxDetectionRange-minDetectionRange)*AmbientLight
+	for _, torch := range []bool{false, true} {
+		level := aiLevel(t, torch, dark...)
+		if got := detectionRange(level.exposure); !near(got, darkRange) {
+			t.Errorf("torch %v: detection range in the dark %v, want %v", torch, got, darkRange)
+		}
+		level = aiLevel(t, torch, lit...)
+		if got := detectionRange(level.exposure); got <= darkRange+1 {
+			t.Errorf("torch %v: detection range next to a brazier %v, want well above %v", torch, got, darkRange)
+		}
+	}
+
+	level := aiLevel(t, true, dark...)
+	if got := level.LightAt(level.Player.Pos).Intensity; got != 1 {
+		t.Errorf("the torch lights the player's tile to %v, want 1", got)
+	}
+	if got := detectionRange(Light{Intensity: 1}); got != maxDetectionRange {
+		t.Errorf("detection range in full light %v, want %v", got, maxDetectionRange)
+	}
+}
@@ -0,0 +1,29 @@
+package game
+
+import (
+	"embed"
+	"path/filepath"
+	"strings"
+)
+
+const defaultMap = "maps/dungeon_csv_Wall.csv"
+
+//go:embed maps
+var embeddedMaps embed.FS
+
+// LoadLevel loads a .csv or .map level by file extension. An empty
+// filename loads the default map built into the binary.
+func LoadLevel(filename string) (*Level, error) {
+	if filename == "" {
+		file, err := embeddedMaps.Open(defaultMap)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return loadLevelFromCSV(file, defaultMap)
+	}
+	if strings.EqualFold(filepath.Ext(filename), ".csv") {
+		return LoadLevelFromCSVFile(filename)
+	}
+	return LoadLevelFromASCIIFile(filename)
+}
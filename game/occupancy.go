@@ -0,0 +1,52 @@
+package game
+
+// The occupancy grid records which enemy stands on each tile so that
+// pathfinding can ask in constant time. It is kept in sync by AddEnemy,
+// Character.Move and removeDeadEnemies; code that moves an enemy any other
+// way must call level.moveOccupant itself.
+
+// EnemyAt returns the enemy standing on pos, dead or alive, or nil.
+func (level *Level) EnemyAt(pos Position) *Enemy {
+	if !level.inBounds(pos) {
+		return nil
+	}
+	return level.occupants[level.index(pos)]
+}
+
+// AddEnemy puts an enemy on the level at its position. It returns false
+// and leaves the level alone when the position is outside the level or
+// another enemy already stands there.
+func (level *Level) AddEnemy(enemy *Enemy) bool {
+	if !level.inBounds(enemy.Pos) || level.EnemyAt(enemy.Pos) != nil {
+		return false
+	}
+	level.Enemies = append(level.Enemies, enemy)
+	level.occupants[level.index(enemy.Pos)] = enemy
+	return true
+}
+
+// moveOccupant updates the grid for a character about to move to pos. The
+// player is not on the grid.
+func (level *Level) moveOccupant(character *Character, pos Position) {
+	enemy := level.EnemyAt(character.Pos)
+	if enemy == nil || &enemy.Character != character {
+		return
+	}
+	level.occupants[level.index(character.Pos)] = nil
+	if level.inBounds(pos) {
+		level.occupants[level.index(pos)] = enemy
+	}
+}
+
+// removeDeadEnemies takes the enemies killed last turn off the level.
+func (level *Level) removeDeadEnemies() {
+	alive := level.Enemies[:0]
+	for _, enemy := range level.Enemies {
+		if !enemy.IsDead {
+			alive = append(alive, enemy)
+		} else if level.EnemyAt(enemy.Pos) == enemy {
+			level.occupants[level.index(enemy.Pos)] = nil
+		}
+	}
+	level.Enemies = alive
+}
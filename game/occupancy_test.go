@@ -0,0 +1,125 @@
+package game
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// clearEnemies takes every enemy off the level.
+func clearEnemies(level *Level) {
+	for _, enemy := range level.Enemies {
+		enemy.IsDead = true
+	}
+	level.removeDeadEnemies()
+}
+
+func TestOccupancy(t *testing.T) {
+	level := levelFromASCII(t,
+		"######",
+		"#....#",
+		"#....#",
+		"######",
+	)
+	a := NewEnemy("a", 1, Position{1, 1})
+	b := NewEnemy("b", 1, Position{2, 2})
+	if !level.AddEnemy(a) || !level.AddEnemy(b) {
+		t.Fatal("enemies not added on free floor")
+	}
+	if level.EnemyAt(Position{1, 1}) != a || level.EnemyAt(Position{2, 2}) != b {
+		t.Fatal("added enemies not found")
+	}
+	if level.AddEnemy(NewEnemy("ghost", 1, Position{1, 1})) || level.AddEnemy(NewEnemy("outside", 1, Position{9, 9})) {
+		t.Error("enemy added on a taken tile or outside the level")
+	}
+	if len(level.Enemies) != 2 || level.EnemyAt(Position{1, 1}) != a {
+		t.Errorf("rejected enemies changed the level: %v", level.Enemies)
+	}
+
+	a.Move(Position{2, 1}, level)
+	if level.EnemyAt(Position{1, 1}) != nil || level.EnemyAt(Position{2, 1}) != a {
+		t.Error("grid not updated by Move")
+	}
+	a.Move(Position{2, 2}, level)
+	if a.Pos != (Position{2, 1}) || level.EnemyAt(Position{2, 2}) != b {
+		t.Error("moved onto another enemy")
+	}
+
+	a.swap(level, b)
+	if level.EnemyAt(Position{2, 1}) != b || level.EnemyAt(Position{2, 2}) != a {
+		t.Error("grid not updated by swap")
+	}
+
+	b.IsDead = true
+	if level.EnemyAt(b.Pos) != b {
+		t.Error("dead enemy left the grid before the end of the turn")
+	}
+	level.removeDeadEnemies()
+	if level.EnemyAt(Position{2, 1}) != nil || len(level.Enemies) != 1 {
+		t.Errorf("dead enemy still on the level: %v", level.Enemies)
+	}
+	if level.EnemyAt(Position{-1, 0}) != nil {
+		t.Error("enemy found outside the level")
+	}
+}
+
+// crowdedLevel is an open width x height room with a pillar on every
+// fourth tile and count enemies on random floor.
+func crowdedLevel(b *testing.B, width, height, count int) *Level {
+	b.Helper()
+	level := NewLevel(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			tile := Floor
+			if x == 0 || y == 0 || x == width-1 || y == height-1 || (x%4 == 2 && y%4 == 2) {
+				tile = Wall
+			}
+			level.setTileType(Position{x, y}, tile)
+		}
+	}
+	rng := rand.New(rand.NewSource(1))
+	for len(level.Enemies) < count {
+		pos := Position{rng.Intn(width), rng.Intn(height)}
+		if canMove(pos, level) && pos != (Position{1, 1}) && pos != (Position{width - 2, height - 2}) {
+			level.AddEnemy(NewEnemy("enemy", 1, pos))
+		}
+	}
+	return level
+}
+
+func BenchmarkCanMove(b *testing.B) {
+	for _, count := range []int{10, 100, 500} {
+		level := crowdedLevel(b, 64, 64, count)
+		b.Run(fmt.Sprintf("%d enemies", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				canMove(Position{i % 64, (i / 64) % 64}, level)
+			}
+		})
+	}
+}
+
+func BenchmarkAstarCrowded(b *testing.B) {
+	for _, count := range []int{10, 100, 500} {
+		level := crowdedLevel(b, 64, 64, count)
+		b.Run(fmt.Sprintf("%d enemies", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				astar(level, Position{1, 1}, Position{62, 62}, pathRules{throughAllies: true})
+			}
+		})
+	}
+}
+
+func TestPlaySkipsTakenSpawns(t *testing.T) {
+	Log = io.Discard
+	// Without a player spawn the player starts on the only floor tile,
+	// which is also the enemy spawn.
+	mapFile := writeTemp(t, "taken.map", "###\n#E#\n###\n")
+	ui := &scriptedUI{}
+	if err := Run(ui, Config{MapFile: mapFile, Seed: 1, EnemyCount: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(ui.level.Enemies); n != 0 {
+		t.Errorf("%d enemies placed on the player's tile", n)
+	}
+}
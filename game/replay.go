@@ -0,0 +1,187 @@
+package game
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// replayVersion is bumped whenever the file format or the rules change in
+// a way that makes older recordings play out differently.
+const (
+	replayHeader  = "hive-master replay"
+	replayVersion = 1
+)
+
+// replay is everything needed to play a recorded game again: the config it
+// started from, the inputs in order and the checksum of the final level.
+type replay struct {
+	config   Config
+	inputs   []InputType
+	checksum uint64
+}
+
+// recorder passes everything through to the wrapped UI and remembers the
+// inputs it returns.
+type recorder struct {
+	GameUI
+	inputs []InputType
+}
+
+func (r *recorder) GetInput() *Input {
+	input := r.GameUI.GetInput()
+	r.inputs = append(r.inputs, input.Type)
+	return input
+}
+
+// replayer draws through the wrapped UI but takes its inputs from a
+// recording, quitting when the recording runs out.
+type replayer struct {
+	GameUI
+	inputs []InputType
+}
+
+func (r *replayer) GetInput() *Input {
+	if len(r.inputs) == 0 {
+		return &Input{Type: Quit}
+	}
+	t := r.inputs[0]
+	r.inputs = r.inputs[1:]
+	return &Input{Type: t}
+}
+
+// Replay plays the game recorded in filename through gameUI and reports an
+// error if the level ends up different from when it was recorded.
+func Replay(gameUI GameUI, filename string) error {
+	r, err := readReplay(filename)
+	if err != nil {
+		return err
+	}
+	level, err := play(&replayer{GameUI: gameUI, inputs: r.inputs}, r.config.withDefaults())
+	if err != nil {
+		return err
+	}
+	if sum := Checksum(level); sum != r.checksum {
+		return fmt.Errorf("%s: replay diverged, checksum %016x, recorded %016x", filename, sum, r.checksum)
+	}
+	return nil
+}
+
+// Checksum hashes the state of the level that the rules change: tiles and
+// doors, keys, switches, traps and secrets, turn and every character.
+func Checksum(level *Level) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintln(h, level.Turn, level.Width, level.Height)
+	// Tiles are hashed by name, as they were before tile types were
+	// numbered, so older replays still check out.
+	for _, tile := range level.tiles {
+		fmt.Fprint(h, tile.TileType.String(), tile.Locked, ",")
+	}
+	fmt.Fprintln(h, len(level.Keys))
+	for _, s := range level.Switches {
+		fmt.Fprint(h, s.On, ",")
+	}
+	for _, trap := range level.Traps {
+		fmt.Fprint(h, trap.Found, ",")
+	}
+	fmt.Fprintln(h, len(level.SecretDoors))
+	writeCharacter := func(c *Character) {
+		fmt.Fprintln(h, c.Name, c.Pos.X, c.Pos.Y, c.Health, c.IsDead)
+	}
+	if level.Player != nil {
+		writeCharacter(&level.Player.Character)
+		fmt.Fprintln(h, level.Player.Keys)
+	}
+	for _, enemy := range level.Enemies {
+		writeCharacter(&enemy.Character)
+		fmt.Fprintln(h, enemy.State)
+	}
+	return h.Sum64()
+}
+
+func writeReplay(filename string, r replay) error {
+	var b strings.Builder
+	fmt.Fprintln(&b, replayHeader, replayVersion)
+	fmt.Fprintln(&b, "seed", r.config.Seed)
+	fmt.Fprintln(&b, "map", r.config.MapFile)
+	fmt.Fprintln(&b, "roster", r.config.RosterFile)
+	fmt.Fprintln(&b, "enemies", r.config.EnemyCount)
+	fmt.Fprintln(&b, "sight", r.config.SightRadius)
+	fmt.Fprintln(&b, "spawn-distance", r.config.MinSpawnDistance)
+	fmt.Fprintln(&b, "spawn-density", r.config.SpawnDensity)
+	for _, t := range r.inputs {
+		fmt.Fprintln(&b, "input", t)
+	}
+	fmt.Fprintf(&b, "checksum %016x\n", r.checksum)
+	if err := os.WriteFile(filename, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to save replay: %v", err)
+	}
+	return nil
+}
+
+func readReplay(filename string) (replay, error) {
+	var r replay
+	file, err := os.Open(filename)
+	if err != nil {
+		return r, fmt.Errorf("failed to open replay: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return r, fmt.Errorf("%s: not a replay file", filename)
+	}
+	version, ok := strings.CutPrefix(scanner.Text(), replayHeader+" ")
+	if !ok {
+		return r, fmt.Errorf("%s: not a replay file", filename)
+	}
+	if version != strconv.Itoa(replayVersion) {
+		return r, fmt.Errorf("%s: unsupported replay version %s, want %d", filename, version, replayVersion)
+	}
+	lineNumber := 1
+	hasChecksum := false
+	for scanner.Scan() {
+		lineNumber++
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		switch key {
+		case "seed":
+			r.config.Seed, err = strconv.ParseInt(value, 10, 64)
+		case "map":
+			r.config.MapFile = value
+		case "roster":
+			r.config.RosterFile = value
+		case "enemies":
+			r.config.EnemyCount, err = strconv.Atoi(value)
+		case "sight":
+			r.config.SightRadius, err = strconv.Atoi(value)
+		case "spawn-distance":
+			r.config.MinSpawnDistance, err = strconv.Atoi(value)
+		case "spawn-density":
+			r.config.SpawnDensity, err = strconv.Atoi(value)
+		case "input":
+			t, ok := parseInputType(value)
+			if !ok {
+				err = fmt.Errorf("unknown input %q", value)
+			}
+			r.inputs = append(r.inputs, t)
+		case "checksum":
+			r.checksum, err = strconv.ParseUint(value, 16, 64)
+			hasChecksum = true
+		default:
+			err = fmt.Errorf("unknown field %q", key)
+		}
+		if err != nil {
+			return r, fmt.Errorf("%s:%d: %v", filename, lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return r, fmt.Errorf("failed to read replay: %v", err)
+	}
+	if !hasChecksum {
+		return r, fmt.Errorf("%s: missing checksum", filename)
+	}
+	return r, nil
+}
@@ -0,0 +1,94 @@
+package game
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// scriptedUI plays a fixed list of inputs, then quits, and keeps the last
+// level it was asked to draw.
+type scriptedUI struct {
+	inputs []InputType
+	level  *Level
+}
+
+func (ui *scriptedUI) Draw(level *Level) {
+	ui.level = level
+}
+
+func (ui *scriptedUI) GetInput() *Input {
+	if len(ui.inputs) == 0 {
+		return &Input{Type: Quit}
+	}
+	t := ui.inputs[0]
+	ui.inputs = ui.inputs[1:]
+	return &Input{Type: t}
+}
+
+func (ui *scriptedUI) NewCharacterLabel(character *Character) {}
+
+func TestRecordAndReplay(t *testing.T) {
+	Log = io.Discard
+	var script []InputType
+	for i := 0; i < 40; i++ {
+		script = append(script, Right, Right, Down, Action, Left, Up, Search)
+	}
+	filename := filepath.Join(t.TempDir(), "game.replay")
+	recorded := &scriptedUI{inputs: script}
+	config := Config{Seed: 7, EnemyCount: 20, RecordFile: filename}
+	if err := Run(recorded, config); err != nil {
+		t.Fatal(err)
+	}
+	if recorded.level.Turn == 0 {
+		t.Fatal("the scripted game did not take a turn")
+	}
+
+	replayed := &scriptedUI{}
+	if err := Replay(replayed, filename); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Checksum(replayed.level), Checksum(recorded.level); got != want {
+		t.Errorf("replay checksum %016x, recorded game %016x", got, want)
+	}
+}
+
+func TestReplayVersion(t *testing.T) {
+	tests := []struct {
+		header string
+		err    string
+	}{
+		{"hive-master replay 0", "unsupported replay version 0"},
+		{"hive-master replay 99", "unsupported replay version 99"},
+		{"hive-master replay", "not a replay file"},
+		{"seed 1", "not a replay file"},
+	}
+	for _, test := range tests {
+		filename := writeTemp(t, "game.replay", test.header+"\nseed 1\nchecksum 0\n")
+		_, err := readReplay(filename)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("header %q: error %v, want %q", test.header, err, test.err)
+		}
+	}
+}
+
+func TestReplayDiverged(t *testing.T) {
+	Log = io.Discard
+	filename := filepath.Join(t.TempDir(), "game.replay")
+	if err := Run(&scriptedUI{inputs: []InputType{Right, Down}}, Config{Seed: 3, RecordFile: filename}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := strings.Replace(string(data), "seed 3", "seed 4", 1)
+	if err := os.WriteFile(filename, []byte(tampered), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Replay(&scriptedUI{}, filename); err == nil || !strings.Contains(err.Error(), "replay diverged") {
+		t.Errorf("error %v, want replay diverged", err)
+	}
+}
@@ -0,0 +1,108 @@
+package game
+
+import "fmt"
+
+// spawner places enemies at random on free floor that the player can walk
+// to, at least minDistance steps from the player, without crowding any
+// room past one enemy per density floor tiles. Maps with a spawn zone only
+// get random enemies inside it.
+type spawner struct {
+	level       *Level
+	minDistance int
+	distance    map[Position]int
+	room        map[Position]int
+	capacity    []int
+	zone        map[Position]bool
+}
+
+func newSpawner(level *Level, minDistance, density int) *spawner {
+	s := &spawner{
+		level:       level,
+		minDistance: minDistance,
+		distance:    BreadthFirstSearch(level, level.Player.Pos),
+		room:        make(map[Position]int),
+	}
+	for y := 0; y < level.Height; y++ {
+		for x := 0; x < level.Width; x++ {
+			pos := Position{x, y}
+			if _, ok := s.room[pos]; ok || !isRoomTile(level, pos) {
+				continue
+			}
+			size := s.fillRoom(pos, len(s.capacity))
+			capacity := size / density
+			if capacity < 1 {
+				capacity = 1
+			}
+			s.capacity = append(s.capacity, capacity)
+		}
+	}
+	if len(level.SpawnZone) > 0 {
+		s.zone = make(map[Position]bool)
+		for _, pos := range level.SpawnZone {
+			s.zone[pos] = true
+		}
+	}
+	return s
+}
+
+// isRoomTile reports whether pos belongs to a room. Doors separate rooms.
+func isRoomTile(level *Level, pos Position) bool {
+	return level.IsPassable(pos) && !isDoor(level, pos) && !level.SecretDoors[pos]
+}
+
+func (s *spawner) fillRoom(start Position, id int) int {
+	s.room[start] = id
+	frontier := []Position{start}
+	size := 0
+	for len(frontier) > 0 {
+		current := frontier[0]
+		frontier = frontier[1:]
+		if s.level.getTileType(current) == Floor {
+			size++
+		}
+		for _, o := range cardinalOffsets {
+			next := Position{current.X + o.X, current.Y + o.Y}
+			if _, ok := s.room[next]; !ok && isRoomTile(s.level, next) {
+				s.room[next] = id
+				frontier = append(frontier, next)
+			}
+		}
+	}
+	return size
+}
+
+// add counts an enemy placed at pos against its room.
+func (s *spawner) add(pos Position) {
+	if room, ok := s.room[pos]; ok {
+		s.capacity[room]--
+	}
+}
+
+func (s *spawner) allowed(pos Position) bool {
+	if !s.level.isFreeFloor(pos) {
+		return false
+	}
+	if d, ok := s.distance[pos]; !ok || d < s.minDistance {
+		return false
+	}
+	if s.zone != nil && !s.zone[pos] {
+		return false
+	}
+	return s.capacity[s.room[pos]] > 0
+}
+
+// next picks a random position for the next enemy.
+func (s *spawner) next() (Position, error) {
+	candidates := make([]Position, 0)
+	for y := 0; y < s.level.Height; y++ {
+		for x := 0; x < s.level.Width; x++ {
+			if pos := (Position{x, y}); s.allowed(pos) {
+				candidates = append(candidates, pos)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return Position{}, fmt.Errorf("no room left for more enemies after %d", len(s.level.Enemies))
+	}
+	return candidates[s.level.rng.Intn(len(candidates))], nil
+}
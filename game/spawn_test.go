@@ -0,0 +1,134 @@
+package game
+
+import "testing"
+
+// spawnAll places enemies with the spawner until it runs out of room.
+func spawnAll(t *testing.T, level *Level, minDistance, density int) []Position {
+	t.Helper()
+	s := newSpawner(level, minDistance, density)
+	var placed []Position
+	for {
+		pos, err := s.next()
+		if err != nil {
+			return placed
+		}
+		s.add(pos)
+		level.AddEnemy(NewEnemy("enemy", 1, pos))
+		placed = append(placed, pos)
+		if len(placed) > level.Width*level.Height {
+			t.Fatal("spawner never ran out of room")
+		}
+	}
+}
+
+func TestSpawnerRules(t *testing.T) {
+	level := levelFromASCII(t,
+		"##################",
+		"#@.......#.......#",
+		"#........|....O..#",
+		"#........#...C...#",
+		"##########.......#",
+		"#......#.#########",
+		"#......#.#",
+		"##########",
+	)
+	level.Player = NewPlayer("player", 1, *level.PlayerSpawn)
+	placed := spawnAll(t, level, 4, 1000)
+
+	distance := BreadthFirstSearch(level, level.Player.Pos)
+	rooms := make(map[bool]int)
+	for _, pos := range placed {
+		if level.getTileType(pos) != Floor {
+			t.Errorf("enemy placed on %s at %v", level.getTileType(pos), pos)
+		}
+		d, ok := distance[pos]
+		if !ok {
+			t.Errorf("enemy placed at %v where the player cannot reach", pos)
+		}
+		if d < 4 {
+			t.Errorf("enemy placed %d steps from the player at %v", d, pos)
+		}
+		rooms[pos.X > 9]++
+	}
+	if len(placed) != 2 || rooms[false] != 1 || rooms[true] != 1 {
+		t.Errorf("placed %v, want one enemy in each reachable room", placed)
+	}
+}
+
+func TestSpawnerDensity(t *testing.T) {
+	level := levelFromASCII(t,
+		"#########",
+		"#@......#",
+		"#.......#",
+		"#.......#",
+		"#########",
+	)
+	level.Player = NewPlayer("player", 1, *level.PlayerSpawn)
+	tests := []struct {
+		density int
+		want    int
+	}{
+		{1000, 1},
+		{8, 2},
+		{1, 20},
+	}
+	for _, test := range tests {
+		clearEnemies(level)
+		if got := len(spawnAll(t, level, 1, test.density)); got != test.want {
+			t.Errorf("density %d placed %d enemies, want %d", test.density, got, test.want)
+		}
+	}
+}
+
+func TestSpawnerZone(t *testing.T) {
+	level := levelFromASCII(t,
+		"##########",
+		"#@.......#",
+		"#......zz#",
+		"#......zz#",
+		"##########",
+	)
+	level.Player = NewPlayer("player", 1, *level.PlayerSpawn)
+	zone := make(map[Position]bool)
+	for _, pos := range level.SpawnZone {
+		zone[pos] = true
+	}
+	placed := spawnAll(t, level, 1, 1)
+	if len(placed) != 4 {
+		t.Errorf("placed %v, want the 4 zone tiles", placed)
+	}
+	for _, pos := range placed {
+		if !zone[pos] {
+			t.Errorf("enemy placed outside the spawn zone at %v", pos)
+		}
+	}
+}
+
+func TestBreadthFirstSearch(t *testing.T) {
+	level := levelFromASCII(t,
+		"#######",
+		"#..|..#",
+		"###.###",
+		"#.###.#",
+		"#######",
+	)
+	distance := BreadthFirstSearch(level, Position{1, 1})
+	tests := []struct {
+		pos      Position
+		distance int
+		reached  bool
+	}{
+		{Position{1, 1}, 0, true},
+		{Position{3, 1}, 2, true},
+		{Position{5, 1}, 4, true},
+		{Position{3, 2}, 3, true},
+		{Position{1, 3}, 0, false},
+		{Position{0, 0}, 0, false},
+	}
+	for _, test := range tests {
+		d, ok := distance[test.pos]
+		if ok != test.reached || d != test.distance {
+			t.Errorf("distance to %v = %d, %v, want %d, %v", test.pos, d, ok, test.distance, test.reached)
+		}
+	}
+}
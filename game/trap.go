@@ -0,0 +1,152 @@
+package game
+
+import (
+	"fmt"
+	"math"
+)
+
+type TrapType int
+
+const (
+	SpikeTrap TrapType = iota
+	AlarmTrap
+	TeleportTrap
+)
+
+var trapNames = [...]string{"spike trap", "alarm trap", "teleport trap"}
+
+func (t TrapType) String() string {
+	if t < 0 || int(t) >= len(trapNames) {
+		return fmt.Sprintf("TrapType(%d)", int(t))
+	}
+	return trapNames[t]
+}
+
+// Trap is hidden until the player finds it by searching, and goes off
+// whenever the player steps on it. Enemies know where their traps are and
+// never set them off.
+type Trap struct {
+	Pos   Position
+	Type  TrapType
+	Found bool
+	// Target is where a teleport trap sends the player, a random free
+	// floor tile when nil.
+	Target *Position
+}
+
+const (
+	spikeDamage  = 20
+	alarmRadius  = 15
+	searchRadius = 2
+
+	secretDoorRune = '+'
+)
+
+// trapRunes are the .map characters of hidden traps. Found traps are shown
+// with the same character.
+var trapRunes = map[rune]TrapType{
+	'^': SpikeTrap,
+	'a': AlarmTrap,
+	'T': TeleportTrap,
+}
+
+func trapRune(t TrapType) rune {
+	for c, trapType := range trapRunes {
+		if trapType == t {
+			return c
+		}
+	}
+	return '?'
+}
+
+func (level *Level) trapAt(pos Position) *Trap {
+	for _, trap := range level.Traps {
+		if trap.Pos == pos {
+			return trap
+		}
+	}
+	return nil
+}
+
+// searchChance is the chance of finding each hidden thing in reach on one
+// search. Experienced players find more.
+func searchChance(player *Player) float64 {
+	return math.Min(0.9, 0.25+0.05*player.Level)
+}
+
+// search looks for hidden traps and secret doors around the player.
+func search(level *Level) {
+	player := level.Player
+	chance := searchChance(player)
+	found := false
+	inReach := func(pos Position) bool {
+		return abs(pos.X-player.Pos.X) <= searchRadius && abs(pos.Y-player.Pos.Y) <= searchRadius &&
+			hasLineOfSight(level, player.Pos, pos)
+	}
+	for _, trap := range level.Traps {
+		if !trap.Found && inReach(trap.Pos) && level.rng.Float64() < chance {
+			trap.Found = true
+			found = true
+			fmt.Fprintln(Log, player.Name, "found a", trap.Type.String()+"!")
+		}
+	}
+	for y := player.Pos.Y - searchRadius; y <= player.Pos.Y+searchRadius; y++ {
+		for x := player.Pos.X - searchRadius; x <= player.Pos.X+searchRadius; x++ {
+			pos := Position{x, y}
+			if level.SecretDoors[pos] && inReach(pos) && level.rng.Float64() < chance {
+				revealSecretDoor(level, pos)
+				found = true
+				fmt.Fprintln(Log, player.Name, "found a secret door!")
+			}
+		}
+	}
+	if !found {
+		fmt.Fprintln(Log, player.Name, "found nothing.")
+	}
+}
+
+// revealSecretDoor turns the wall at pos into the closed door it hides.
+func revealSecretDoor(level *Level, pos Position) {
+	delete(level.SecretDoors, pos)
+	level.setTileType(pos, ClosedDoorV)
+	Autotile(level)
+}
+
+// springTrap sets off the trap under the player, if there is one.
+func springTrap(level *Level) {
+	player := level.Player
+	trap := level.trapAt(player.Pos)
+	if trap == nil {
+		return
+	}
+	trap.Found = true
+	switch trap.Type {
+	case SpikeTrap:
+		fmt.Fprintln(Log, player.Name, "stepped on a spike trap for", spikeDamage, "damage!")
+		player.Health -= spikeDamage
+		if player.Health <= 0 {
+			fmt.Fprintln(Log, player.Name, "is dead.")
+			player.IsDead = true
+		}
+	case AlarmTrap:
+		fmt.Fprintln(Log, "an alarm rings out!")
+		for _, enemy := range level.Enemies {
+			if !enemy.IsDead && enemy.State != Chasing && enemy.State != Fleeing &&
+				enemy.distanceToCharacter(&player.Character) <= alarmRadius {
+				enemy.investigate(player.Pos)
+			}
+		}
+	case TeleportTrap:
+		var to Position
+		if trap.Target != nil && level.isFreeFloor(*trap.Target) {
+			to = *trap.Target
+		} else if pos, err := level.getRandomPosition(); err == nil {
+			to = pos
+		} else {
+			return
+		}
+		fmt.Fprintln(Log, player.Name, "was teleported!")
+		player.Pos = to
+		pickUpKey(level)
+	}
+}
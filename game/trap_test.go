@@ -0,0 +1,123 @@
+package game
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var trapRows = []string{
+	"##########",
+	"#@^.a.T..#",
+	"####+#####",
+	"#........#",
+	"##########",
+	"---",
+	"teleport 2:7 4:9",
+}
+
+func trapLevel(t *testing.T) *Level {
+	t.Helper()
+	Log = io.Discard
+	level := levelFromASCII(t, trapRows...)
+	level.Player = NewPlayer("player", 10, *level.PlayerSpawn)
+	return level
+}
+
+func TestSaveTrapsRoundTrip(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "level.map")
+	if err := SaveLevelToASCIIFile(trapLevel(t), filename); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), strings.Join(trapRows, "\n")+"\n"; got != want {
+		t.Errorf("saved level:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	level := trapLevel(t)
+	if _, ok := level.ObjectRune(Position{2, 1}); ok {
+		t.Error("hidden trap shown before it was found")
+	}
+	level.Player.Pos = Position{4, 1}
+	allFound := func() bool {
+		for _, trap := range level.Traps {
+			if !trap.Found {
+				return false
+			}
+		}
+		return len(level.SecretDoors) == 0
+	}
+	for i := 0; i < 50 && !allFound(); i++ {
+		handleInput(level, &Input{Type: Search})
+	}
+	if !level.Traps[0].Found || !level.Traps[1].Found || !level.Traps[2].Found {
+		t.Errorf("traps in reach not found after searching: %v %v %v", level.Traps[0], level.Traps[1], level.Traps[2])
+	}
+	if len(level.SecretDoors) > 0 || !isClosedDoor(level, Position{4, 2}) {
+		t.Error("secret door not found after searching")
+	}
+	if c, ok := level.ObjectRune(Position{2, 1}); !ok || c != '^' {
+		t.Errorf("found trap shown as %q, %v, want '^'", c, ok)
+	}
+}
+
+func TestSearchOutOfReach(t *testing.T) {
+	level := trapLevel(t)
+	level.Player.Pos = Position{8, 3}
+	for i := 0; i < 20; i++ {
+		handleInput(level, &Input{Type: Search})
+	}
+	for _, trap := range level.Traps {
+		if trap.Found {
+			t.Errorf("found %s at %v behind a wall", trap.Type, trap.Pos)
+		}
+	}
+}
+
+func TestSpringTrap(t *testing.T) {
+	level := trapLevel(t)
+	level.AddEnemy(NewEnemy("near", 1, Position{8, 1}))
+	level.AddEnemy(NewEnemy("other room", 1, Position{1, 3}))
+
+	handleInput(level, &Input{Type: Right})
+	if level.Player.Health != 100-spikeDamage || !level.Traps[0].Found {
+		t.Errorf("spike trap left health at %d", level.Player.Health)
+	}
+	handleInput(level, &Input{Type: Left})
+	if level.Player.Health != 100-spikeDamage {
+		t.Error("stepping off a trap set it off again")
+	}
+
+	level.Player.Pos = Position{3, 1}
+	handleInput(level, &Input{Type: Right})
+	for _, enemy := range level.Enemies {
+		if enemy.State != Searching || enemy.lastKnown != (Position{4, 1}) {
+			t.Errorf("%s is %s towards %v after the alarm, want search towards {4 1}", enemy.Name, enemy.State, enemy.lastKnown)
+		}
+	}
+
+	level.Player.Pos = Position{5, 1}
+	handleInput(level, &Input{Type: Right})
+	if level.Player.Pos != (Position{8, 3}) {
+		t.Errorf("teleport trap sent the player to %v, want {8 3}", level.Player.Pos)
+	}
+}
+
+func TestTrapTypeString(t *testing.T) {
+	if got := TeleportTrap.String(); got != "teleport trap" {
+		t.Errorf("TeleportTrap = %q, want teleport trap", got)
+	}
+	for _, trap := range []TrapType{-1, TrapType(len(trapNames))} {
+		if got, want := trap.String(), fmt.Sprintf("TrapType(%d)", int(trap)); got != want {
+			t.Errorf("%d printed as %q, want %q", int(trap), got, want)
+		}
+	}
+}
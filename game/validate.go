@@ -0,0 +1,157 @@
+package game
+
+import "fmt"
+
+// LevelError is a problem found at one tile of a level. Pos is
+// {-1, -1} for problems with the level as a whole.
+type LevelError struct {
+	Pos Position
+	Msg string
+}
+
+// Error reports the position as line:column, like the map loaders.
+func (e *LevelError) Error() string {
+	if e.Pos.X < 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Pos.Y+1, e.Pos.X+1, e.Msg)
+}
+
+// openArea is a connected set of passable tiles, found first at its top
+// left tile.
+type openArea struct {
+	first Position
+	size  int
+}
+
+var cardinalOffsets = []Position{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+
+// ValidateLevel checks that a loaded level is playable: every walkable
+// tile is enclosed by walls and reachable from the player spawn, or from
+// the largest open area without one, the spawn points and zone stand on
+// free floor and every locked door can be opened.
+func ValidateLevel(level *Level) []*LevelError {
+	var errs []*LevelError
+	var regions []openArea
+	region := make(map[Position]int)
+	for y := 0; y < level.Height; y++ {
+		for x := 0; x < level.Width; x++ {
+			pos := Position{x, y}
+			if !level.IsPassable(pos) {
+				continue
+			}
+			if _, ok := region[pos]; !ok {
+				reached := floodPassable(level, pos)
+				for p := range reached {
+					region[p] = len(regions)
+				}
+				regions = append(regions, openArea{pos, len(reached)})
+			}
+			for _, o := range cardinalOffsets {
+				n := Position{pos.X + o.X, pos.Y + o.Y}
+				if !level.inBounds(n) || isBlank(level, n) {
+					errs = append(errs, &LevelError{pos, "floor is not enclosed by walls"})
+					break
+				}
+			}
+		}
+	}
+	if len(regions) == 0 {
+		return append(errs, &LevelError{Position{-1, -1}, "level has no floor"})
+	}
+
+	main := 0
+	for i, r := range regions {
+		if r.size > regions[main].size {
+			main = i
+		}
+	}
+	from := "the largest open area"
+	if spawn := level.PlayerSpawn; spawn != nil {
+		if !level.inBounds(*spawn) || !canMove(*spawn, level) {
+			errs = append(errs, &LevelError{*spawn, "player spawn is not on free floor"})
+		} else {
+			main = region[*spawn]
+			from = "the player spawn"
+		}
+	}
+	seen := make(map[Position]bool)
+	for i, pos := range level.EnemySpawns {
+		switch {
+		case !level.inBounds(pos) || !canMove(pos, level):
+			errs = append(errs, &LevelError{pos, "enemy spawn is not on free floor"})
+		case level.PlayerSpawn != nil && pos == *level.PlayerSpawn:
+			errs = append(errs, &LevelError{pos, "enemy spawn on the player spawn"})
+		case seen[pos]:
+			errs = append(errs, &LevelError{pos, fmt.Sprintf("enemy spawn %d repeats an earlier one", i+1)})
+		}
+		seen[pos] = true
+	}
+	for _, pos := range level.SpawnZone {
+		if !level.inBounds(pos) || level.getTileType(pos) != Floor {
+			errs = append(errs, &LevelError{pos, "spawn zone is not on floor"})
+		}
+	}
+	errs = append(errs, validateWiring(level)...)
+
+	for i, r := range regions {
+		if i != main {
+			errs = append(errs, &LevelError{r.first, fmt.Sprintf("%d tiles cannot be reached from %s", r.size, from)})
+		}
+	}
+	return errs
+}
+
+// validateWiring checks that keys and switches lie on floor, teleport
+// traps lead to free floor and every locked door has a key or a switch to
+// open it.
+func validateWiring(level *Level) []*LevelError {
+	var errs []*LevelError
+	wired := make(map[Position]bool)
+	for _, s := range level.Switches {
+		if level.getTileType(s.Pos) != Floor {
+			errs = append(errs, &LevelError{s.Pos, "switch is not on floor"})
+		}
+		for _, door := range s.Doors {
+			wired[door] = true
+		}
+	}
+	for _, trap := range level.Traps {
+		if trap.Target != nil && !level.isFreeFloor(*trap.Target) {
+			errs = append(errs, &LevelError{trap.Pos, "teleport target is not on free floor"})
+		}
+	}
+	keys := make(map[string]bool)
+	for _, name := range level.Keys {
+		keys[name] = true
+	}
+	for y := 0; y < level.Height; y++ {
+		for x := 0; x < level.Width; x++ {
+			pos := Position{x, y}
+			if _, ok := level.Keys[pos]; ok && level.getTileType(pos) != Floor {
+				errs = append(errs, &LevelError{pos, "key is not on floor"})
+			}
+			if name := level.Locks[pos]; level.Tile(pos).Locked && !wired[pos] && !keys[name] {
+				errs = append(errs, &LevelError{pos, fmt.Sprintf("locked door needs %s, which is not in the level", keyName(name))})
+			}
+		}
+	}
+	return errs
+}
+
+func floodPassable(level *Level, start Position) map[Position]bool {
+	reached := map[Position]bool{start: true}
+	frontier := []Position{start}
+	for len(frontier) > 0 {
+		current := frontier[0]
+		frontier = frontier[1:]
+		for _, o := range cardinalOffsets {
+			next := Position{current.X + o.X, current.Y + o.Y}
+			if !reached[next] && level.IsPassable(next) {
+				reached[next] = true
+				frontier = append(frontier, next)
+			}
+		}
+	}
+	return reached
+}
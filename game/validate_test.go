@@ -0,0 +1,147 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []string
+		errs []string
+	}{
+		{
+			name: "valid",
+			rows: []string{
+				"#######",
+				"#@..E.#",
+				"#.*.|.#",
+				"#######",
+			},
+		},
+		{
+			name: "no floor",
+			rows: []string{
+				"###",
+				"###",
+			},
+			errs: []string{"level has no floor"},
+		},
+		{
+			name: "open to the void",
+			rows: []string{
+				"#####",
+				"#@.. ",
+				"#####",
+			},
+			errs: []string{"2:4: floor is not enclosed by walls"},
+		},
+		{
+			name: "open to the edge",
+			rows: []string{
+				"##.##",
+				"#@..#",
+				"#####",
+			},
+			errs: []string{"1:3: floor is not enclosed by walls"},
+		},
+		{
+			name: "unreachable room",
+			rows: []string{
+				"#########",
+				"#@.#....#",
+				"#..#....#",
+				"#########",
+			},
+			errs: []string{"2:5: 8 tiles cannot be reached from the player spawn"},
+		},
+		{
+			name: "smaller room without a spawn",
+			rows: []string{
+				"#########",
+				"#..#....#",
+				"#..#....#",
+				"#########",
+			},
+			errs: []string{"2:2: 4 tiles cannot be reached from the largest open area"},
+		},
+		{
+			name: "enemy spawn on the wrong side",
+			rows: []string{
+				"#######",
+				"#@.#.E#",
+				"#######",
+			},
+			errs: []string{"2:5: 2 tiles cannot be reached from the player spawn"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := validationErrors(levelFromASCII(t, test.rows...))
+			if want := strings.Join(test.errs, "\n"); got != want {
+				t.Errorf("errors:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func validationErrors(level *Level) string {
+	var errs []string
+	for _, err := range ValidateLevel(level) {
+		errs = append(errs, err.Error())
+	}
+	return strings.Join(errs, "\n")
+}
+
+// level1.map used to have a door at 8:24 that led out of the level.
+func TestLevel1Valid(t *testing.T) {
+	level, err := LoadLevel("maps/level1.map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := validationErrors(level); errs != "" {
+		t.Errorf("maps/level1.map:\n%s", errs)
+	}
+}
+
+func TestValidateLevelSpawns(t *testing.T) {
+	level := levelFromASCII(t,
+		"######",
+		"#@.E.#",
+		"#.*..#",
+		"######",
+	)
+	level.EnemySpawns = append(level.EnemySpawns, Position{3, 1}, Position{1, 1}, Position{2, 2})
+	want := strings.Join([]string{
+		"2:4: enemy spawn 2 repeats an earlier one",
+		"2:2: enemy spawn on the player spawn",
+		"3:3: enemy spawn is not on free floor",
+	}, "\n")
+	if got := validationErrors(level); got != want {
+		t.Errorf("errors:\n%s\nwant:\n%s", got, want)
+	}
+
+	level.EnemySpawns = nil
+	level.PlayerSpawn = &Position{0, 0}
+	want = "1:1: player spawn is not on free floor"
+	if got := validationErrors(level); got != want {
+		t.Errorf("errors:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGetRandomPositionNoFloor(t *testing.T) {
+	level := levelFromASCII(t,
+		"###",
+		"#.#",
+		"###",
+	)
+	pos, err := level.getRandomPosition()
+	if err != nil || pos != (Position{1, 1}) {
+		t.Fatalf("getRandomPosition() = %v, %v, want {1 1}", pos, err)
+	}
+	level.AddEnemy(NewEnemy("enemy", 1, pos))
+	if _, err := level.getRandomPosition(); err == nil {
+		t.Error("getRandomPosition() found a position on a full level")
+	}
+}
@@ -0,0 +1,269 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Switch is a lever that opens and closes the doors wired to it, locked or
+// not.
+type Switch struct {
+	Pos   Position
+	Doors []Position
+	On    bool
+}
+
+const (
+	keyRune    = 'k'
+	switchRune = '!'
+
+	// wiringSeparator ends the tiles of a .map file. The lines after it
+	// name keys and locks, wire switches to doors and aim teleport traps,
+	// with positions written as line:column:
+	//
+	//	key 3:5 red
+	//	lock 8:24 red
+	//	switch 4:10 8:24 8:30
+	//	teleport 5:3 12:40
+	wiringSeparator = "---"
+)
+
+// ObjectRune returns the .map character for a key, switch or found trap at
+// pos.
+func (level *Level) ObjectRune(pos Position) (rune, bool) {
+	if trap := level.trapAt(pos); trap != nil && trap.Found {
+		return trapRune(trap.Type), true
+	}
+	if _, ok := level.Keys[pos]; ok {
+		return keyRune, true
+	}
+	if level.switchAt(pos) != nil {
+		return switchRune, true
+	}
+	return 0, false
+}
+
+// RemoveObjects takes away the key, switch, trap or secret door at pos.
+func (level *Level) RemoveObjects(pos Position) {
+	delete(level.Keys, pos)
+	delete(level.SecretDoors, pos)
+	for i, trap := range level.Traps {
+		if trap.Pos == pos {
+			level.Traps = append(level.Traps[:i], level.Traps[i+1:]...)
+			break
+		}
+	}
+	for i, s := range level.Switches {
+		if s.Pos == pos {
+			level.Switches = append(level.Switches[:i], level.Switches[i+1:]...)
+			return
+		}
+	}
+}
+
+func (level *Level) switchAt(pos Position) *Switch {
+	for _, s := range level.Switches {
+		if s.Pos == pos {
+			return s
+		}
+	}
+	return nil
+}
+
+// parseWiring reads the lines after the wiring separator. first is the line
+// number of lines[0].
+func parseWiring(level *Level, lines []string, first int, name string) error {
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "//") {
+			continue
+		}
+		if err := parseWire(level, fields); err != nil {
+			return fmt.Errorf("%s:%d: %v", name, first+i, err)
+		}
+	}
+	return nil
+}
+
+func parseWire(level *Level, fields []string) error {
+	if len(fields) < 2 {
+		return fmt.Errorf("%s needs a position", fields[0])
+	}
+	pos, err := parseMapPosition(level, fields[1])
+	if err != nil {
+		return err
+	}
+	switch fields[0] {
+	case "key":
+		if len(fields) != 3 {
+			return fmt.Errorf("key needs a position and a name")
+		}
+		level.Keys[pos] = fields[2]
+	case "lock":
+		if len(fields) != 3 {
+			return fmt.Errorf("lock needs a position and a key name")
+		}
+		if !isDoor(level, pos) {
+			return fmt.Errorf("lock at %s is not on a door", fields[1])
+		}
+		// A locked door cannot be toggled, an open one would never close.
+		if !isClosedDoor(level, pos) {
+			return fmt.Errorf("lock at %s is on an open door", fields[1])
+		}
+		level.setLocked(pos, true)
+		level.Locks[pos] = fields[2]
+	case "teleport":
+		if len(fields) != 3 {
+			return fmt.Errorf("teleport needs a trap position and a target")
+		}
+		trap := level.trapAt(pos)
+		if trap == nil || trap.Type != TeleportTrap {
+			return fmt.Errorf("no teleport trap at %s", fields[1])
+		}
+		target, err := parseMapPosition(level, fields[2])
+		if err != nil {
+			return err
+		}
+		trap.Target = &target
+	case "switch":
+		if len(fields) < 3 {
+			return fmt.Errorf("switch needs a position and at least one door")
+		}
+		s := level.switchAt(pos)
+		if s == nil {
+			s = &Switch{Pos: pos}
+			level.Switches = append(level.Switches, s)
+		}
+		for _, field := range fields[2:] {
+			door, err := parseMapPosition(level, field)
+			if err != nil {
+				return err
+			}
+			if !isDoor(level, door) {
+				return fmt.Errorf("switch wired to %s, which is not a door", field)
+			}
+			s.Doors = append(s.Doors, door)
+		}
+	default:
+		return fmt.Errorf("unknown wiring %q", fields[0])
+	}
+	return nil
+}
+
+// parseMapPosition reads line:column, counted from 1.
+func parseMapPosition(level *Level, s string) (Position, error) {
+	line, col, ok := strings.Cut(s, ":")
+	y, err1 := strconv.Atoi(line)
+	x, err2 := strconv.Atoi(col)
+	if !ok || err1 != nil || err2 != nil {
+		return Position{}, fmt.Errorf("bad position %q, want line:column", s)
+	}
+	pos := Position{x - 1, y - 1}
+	if !level.inBounds(pos) {
+		return Position{}, fmt.Errorf("position %s is outside the level", s)
+	}
+	return pos, nil
+}
+
+func formatMapPosition(pos Position) string {
+	return fmt.Sprintf("%d:%d", pos.Y+1, pos.X+1)
+}
+
+// writeWiring writes the wiring section of a .map file, or nothing when
+// every key and lock is unnamed, no switch has doors and no teleport trap
+// has a target.
+func writeWiring(b *strings.Builder, level *Level) {
+	var lines []string
+	for pos, name := range level.Keys {
+		if name != "" {
+			lines = append(lines, fmt.Sprintf("key %s %s", formatMapPosition(pos), name))
+		}
+	}
+	for pos, name := range level.Locks {
+		if name != "" && level.Tile(pos).Locked {
+			lines = append(lines, fmt.Sprintf("lock %s %s", formatMapPosition(pos), name))
+		}
+	}
+	// Map order is random, keep saved files stable.
+	sort.Strings(lines)
+	for _, trap := range level.Traps {
+		if trap.Target != nil {
+			lines = append(lines, fmt.Sprintf("teleport %s %s", formatMapPosition(trap.Pos), formatMapPosition(*trap.Target)))
+		}
+	}
+	for _, s := range level.Switches {
+		if len(s.Doors) == 0 {
+			continue
+		}
+		doors := make([]string, len(s.Doors))
+		for i, door := range s.Doors {
+			doors[i] = formatMapPosition(door)
+		}
+		lines = append(lines, fmt.Sprintf("switch %s %s", formatMapPosition(s.Pos), strings.Join(doors, " ")))
+	}
+	if len(lines) == 0 {
+		return
+	}
+	b.WriteString(wiringSeparator + "\n")
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+}
+
+// pickUpKey moves a key lying at the player's feet into their pockets.
+func pickUpKey(level *Level) {
+	player := level.Player
+	name, ok := level.Keys[player.Pos]
+	if !ok {
+		return
+	}
+	delete(level.Keys, player.Pos)
+	player.Keys = append(player.Keys, name)
+	fmt.Fprintln(Log, player.Name, "picked up", keyName(name))
+}
+
+// unlockDoor unlocks the door at pos if the player carries its key.
+func unlockDoor(level *Level, pos Position) {
+	if !level.Tile(pos).Locked {
+		return
+	}
+	name := level.Locks[pos]
+	for _, key := range level.Player.Keys {
+		if key == name {
+			level.setLocked(pos, false)
+			fmt.Fprintln(Log, level.Player.Name, "unlocked the door with", keyName(name))
+			return
+		}
+	}
+}
+
+func keyName(name string) string {
+	if name == "" {
+		return "a key"
+	}
+	return "the " + name + " key"
+}
+
+// pullSwitches pulls every switch on or next to pos.
+func pullSwitches(level *Level, pos Position) {
+	for _, s := range level.Switches {
+		if abs(s.Pos.X-pos.X)+abs(s.Pos.Y-pos.Y) > 1 {
+			continue
+		}
+		s.On = !s.On
+		fmt.Fprintln(Log, level.Player.Name, "pulled a lever.")
+		for _, door := range s.Doors {
+			if hasCharacter(level, door) {
+				continue
+			}
+			toggleDoor(door, level)
+		}
+	}
+}
+
+func hasCharacter(level *Level, pos Position) bool {
+	exists, _ := hasEnemy(pos, level)
+	return exists || (level.Player != nil && level.Player.Pos == pos)
+}
@@ -0,0 +1,132 @@
+package game
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var wiredRows = []string{
+	"#########",
+	"#@.k.=..#",
+	"#!..#####",
+	"##=##",
+	"#...#",
+	"#####",
+	"---",
+	"key 2:4 red",
+	"lock 2:6 red",
+	"switch 3:2 4:3",
+}
+
+func TestLoadWiring(t *testing.T) {
+	level := levelFromASCII(t, wiredRows...)
+	if name, ok := level.Keys[Position{3, 1}]; !ok || name != "red" {
+		t.Errorf("key at {3 1} = %q, %v, want red", name, ok)
+	}
+	if !level.Tile(Position{5, 1}).Locked || level.Locks[Position{5, 1}] != "red" {
+		t.Errorf("door at {5 1} locked = %v with %q, want the red key", level.Tile(Position{5, 1}).Locked, level.Locks[Position{5, 1}])
+	}
+	if len(level.Switches) != 1 || level.Switches[0].Pos != (Position{1, 2}) ||
+		len(level.Switches[0].Doors) != 1 || level.Switches[0].Doors[0] != (Position{2, 3}) {
+		t.Errorf("switches = %v, want one at {1 2} wired to {2 3}", level.Switches)
+	}
+	if errs := validationErrors(level); errs != "" {
+		t.Errorf("validation errors:\n%s", errs)
+	}
+}
+
+func TestLoadWiringErrors(t *testing.T) {
+	tests := []struct {
+		wire string
+		err  string
+	}{
+		{"key 2:2", "test.map:5: key needs a position and a name"},
+		{"lock 2:2 red", "test.map:5: lock at 2:2 is not on a door"},
+		{"lock 2:5 red", "test.map:5: lock at 2:5 is on an open door"},
+		{"switch 2:2 9:9", "test.map:5: position 9:9 is outside the level"},
+		{"switch 2:2 2:x", `test.map:5: bad position "2:x", want line:column`},
+		{"switch 2:2 1:1", "test.map:5: switch wired to 1:1, which is not a door"},
+		{"door 2:2", `test.map:5: unknown wiring "door"`},
+	}
+	for _, test := range tests {
+		t.Run(test.wire, func(t *testing.T) {
+			ascii := strings.Join([]string{"######", "#@.|/#", "######", "---", test.wire}, "\n")
+			_, err := loadLevelFromASCII(strings.NewReader(ascii), "test.map")
+			if err == nil || err.Error() != test.err {
+				t.Errorf("error = %v, want %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestSaveWiringRoundTrip(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "level.map")
+	if err := SaveLevelToASCIIFile(levelFromASCII(t, wiredRows...), filename); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), strings.Join(wiredRows, "\n")+"\n"; got != want {
+		t.Errorf("saved level:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestKeysAndSwitches(t *testing.T) {
+	Log = io.Discard
+	level := levelFromASCII(t, wiredRows...)
+	level.Player = NewPlayer("player", 1, *level.PlayerSpawn)
+	move := func(inputs ...InputType) {
+		for _, input := range inputs {
+			handleInput(level, &Input{Type: input})
+		}
+	}
+
+	move(Right, Right, Right)
+	if level.Player.Pos != (Position{4, 1}) || len(level.Player.Keys) != 1 {
+		t.Fatalf("player at %v with keys %v, want {4 1} with the red key", level.Player.Pos, level.Player.Keys)
+	}
+	move(Right)
+	if level.Tile(Position{5, 1}).Locked || isClosedDoor(level, Position{5, 1}) {
+		t.Errorf("bumping the locked door with its key left it locked %v, closed %v", level.Tile(Position{5, 1}).Locked, isClosedDoor(level, Position{5, 1}))
+	}
+
+	move(Left, Left, Down)
+	if level.Player.Pos != (Position{2, 2}) {
+		t.Fatalf("player at %v, want next to the switch", level.Player.Pos)
+	}
+	door := Position{2, 3}
+	move(Down)
+	if !isClosedDoor(level, door) {
+		t.Fatal("door without a key opened by hand")
+	}
+	move(Action)
+	if isClosedDoor(level, door) || !level.Switches[0].On {
+		t.Fatal("switch did not open the door")
+	}
+	move(Action)
+	if !isClosedDoor(level, door) || level.Switches[0].On {
+		t.Error("switch did not close the door again")
+	}
+}
+
+func TestValidateWiring(t *testing.T) {
+	level := levelFromASCII(t,
+		"#######",
+		"#@.=.|#",
+		"#######",
+		"---",
+		"lock 2:6 blue",
+	)
+	want := strings.Join([]string{
+		"2:4: locked door needs a key, which is not in the level",
+		"2:6: locked door needs the blue key, which is not in the level",
+	}, "\n")
+	if got := validationErrors(level); got != want {
+		t.Errorf("errors:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -1,13 +1,41 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
 	"github.com/wehard/hive-master/game"
+	"github.com/wehard/hive-master/sim"
 	"github.com/wehard/hive-master/ui"
 )
 
 //var AuthorizedClientCredentials ftapi.ClientCredentials
 
+const (
+	newMapWidth  = 64
+	newMapHeight = 32
+)
+
 func main() {
+	mapFile := flag.String("map", "", "play the level in `file` (.csv or .map) instead of the built-in map")
+	seed := flag.Int64("seed", 0, "random seed, 0 picks one from the clock")
+	frontend := flag.String("ui", "sdl", "frontend to use: sdl, term or headless")
+	rosterFile := flag.String("roster", "", "load enemy names and levels from `file` instead of the built-in enemies")
+	enemyCount := flag.Int("enemies", 50, "number of enemies")
+	sightRadius := flag.Int("sight", 50, "player sight radius in tiles")
+	assetDir := flag.String("assets", "", "load sprites and fonts from `dir` instead of the built-in assets")
+	recordFile := flag.String("record", "", "save the seed and every input to `file`")
+	replayFile := flag.String("replay", "", "play back the game recorded in `file` and check it ends the same way")
+	simGames := flag.Int("sim", 0, "play `n` seeded games with a bot and print balance statistics")
+	simTurns := flag.Int("turns", 500, "turn limit for each -sim game")
+	editFile := flag.String("edit", "", "open the level editor on `file` instead of playing")
+	flag.Parse()
+
+	if flag.Arg(0) == "validate-map" {
+		os.Exit(validateMaps(flag.Args()[1:]))
+	}
 
 	//clientCredentials := ftapi.Authorize()
 	//game.AuthorizedClientCredentials = clientCredentials
@@ -22,6 +50,119 @@ func main() {
 	//	}
 	//}
 
-	ui := &ui.UI2d{}
-	game.Run(ui)
+	config := game.Config{
+		MapFile:     *mapFile,
+		RosterFile:  *rosterFile,
+		Seed:        *seed,
+		EnemyCount:  *enemyCount,
+		SightRadius: *sightRadius,
+		RecordFile:  *recordFile,
+	}
+
+	if *simGames > 0 {
+		if config.Seed == 0 {
+			config.Seed = 1
+		}
+		report, err := sim.Simulate(config, *simGames, *simTurns)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		report.Print(os.Stdout)
+		return
+	}
+
+	switch *frontend {
+	case "term", "headless":
+		out := io.Writer(os.Stdout)
+		if *frontend == "headless" {
+			out = io.Discard
+		}
+		termUI := ui.NewUITerm(os.Stdin, out)
+		err := run(termUI, config, *replayFile)
+		termUI.Close()
+		exitOnError(err)
+		return
+	case "sdl":
+	default:
+		fmt.Println("unknown ui:", *frontend)
+		os.Exit(2)
+	}
+
+	sdlUI, err := ui.NewUI2d(ui.Config{CameraDeadZone: 4, AssetDir: *assetDir})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if *editFile != "" {
+		err = edit(sdlUI, *editFile)
+	} else {
+		err = run(sdlUI, config, *replayFile)
+	}
+	sdlUI.Close()
+	exitOnError(err)
+}
+
+// edit opens the level editor on filename, or on a new level when the file
+// does not exist yet.
+func edit(sdlUI *ui.UI2d, filename string) error {
+	level := game.NewLevel(newMapWidth, newMapHeight)
+	if _, err := os.Stat(filename); err == nil {
+		level, err = game.LoadLevelFromASCIIFile(filename)
+		if err != nil {
+			return err
+		}
+	}
+	return sdlUI.RunEditor(level, filename)
+}
+
+// validateMaps checks each map file, or the built-in map when none are
+// given, and returns the exit status.
+func validateMaps(files []string) int {
+	if len(files) == 0 {
+		files = []string{""}
+	}
+	status := 0
+	for _, file := range files {
+		name := file
+		if name == "" {
+			name = "built-in map"
+		}
+		level, err := game.LoadLevel(file)
+		if err != nil {
+			fmt.Println(err)
+			status = 1
+			continue
+		}
+		errs := game.ValidateLevel(level)
+		for _, err := range errs {
+			sep := ":"
+			if err.Pos.X < 0 {
+				sep = ": "
+			}
+			fmt.Println(name + sep + err.Error())
+		}
+		if len(errs) > 0 {
+			status = 1
+			continue
+		}
+		fmt.Println(name + ": ok")
+	}
+	return status
+}
+
+func run(gameUI game.GameUI, config game.Config, replayFile string) error {
+	if replayFile != "" {
+		return game.Replay(gameUI, replayFile)
+	}
+	return game.Run(gameUI, config)
+}
+
+// exitOnError prints err and exits. Call it only after the UI is closed,
+// os.Exit skips deferred calls.
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 }
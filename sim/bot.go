@@ -0,0 +1,168 @@
+package sim
+
+import (
+	"math/rand"
+
+	"github.com/wehard/hive-master/game"
+)
+
+const (
+	chaseRange = 8
+	fleeRange  = 3
+	fleeHealth = 30
+)
+
+var directions = []struct {
+	input  game.InputType
+	dx, dy int
+}{
+	{game.Up, 0, -1},
+	{game.Down, 0, 1},
+	{game.Left, -1, 0},
+	{game.Right, 1, 0},
+}
+
+// Player decides the player's next input from the level as drawn.
+type Player interface {
+	Next(level *game.Level) game.InputType
+}
+
+// Script plays a fixed list of inputs and then quits.
+type Script []game.InputType
+
+func (s *Script) Next(level *game.Level) game.InputType {
+	if len(*s) == 0 {
+		return game.Quit
+	}
+	t := (*s)[0]
+	*s = (*s)[1:]
+	return t
+}
+
+// Bot fights enemies next to it, chases the ones it can see, flees from
+// aggressive ones when its health runs low and otherwise walks to the
+// nearest tile it has not seen yet.
+type Bot struct {
+	rng *rand.Rand
+}
+
+func NewBot(seed int64) *Bot {
+	return &Bot{rng: rand.New(rand.NewSource(seed))}
+}
+
+func step(pos game.Position, i int) game.Position {
+	return game.Position{X: pos.X + directions[i].dx, Y: pos.Y + directions[i].dy}
+}
+
+func distance(a, b game.Position) int {
+	return abs(a.X-b.X) + abs(a.Y-b.Y)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func enemyAt(level *game.Level, pos game.Position) *game.Enemy {
+	if e := level.EnemyAt(pos); e != nil && !e.IsDead {
+		return e
+	}
+	return nil
+}
+
+// passable reports whether the player can walk onto pos as far as they
+// know. Secret doors they have not found are walls to them.
+func passable(level *game.Level, pos game.Position) bool {
+	return !level.SecretDoors[pos] && level.IsPassable(pos)
+}
+
+func (b *Bot) Next(level *game.Level) game.InputType {
+	player := level.Player
+	if player.Health < fleeHealth {
+		if t, ok := b.flee(level); ok {
+			return t
+		}
+	}
+	for i, d := range directions {
+		if enemyAt(level, step(player.Pos, i)) != nil {
+			return d.input
+		}
+	}
+	if t, ok := b.pathTo(level, func(pos game.Position) bool {
+		e := enemyAt(level, pos)
+		return e != nil && level.IsVisible(pos) && distance(player.Pos, pos) <= chaseRange
+	}); ok {
+		return t
+	}
+	if t, ok := b.pathTo(level, func(pos game.Position) bool {
+		return !level.IsVisited(pos)
+	}); ok {
+		return t
+	}
+	return directions[b.rng.Intn(len(directions))].input
+}
+
+// flee steps away from the closest enemy chasing it in fleeRange.
+func (b *Bot) flee(level *game.Level) (game.InputType, bool) {
+	player := level.Player
+	var threat *game.Enemy
+	for _, e := range level.Enemies {
+		if !e.IsDead && e.State == game.Chasing && distance(player.Pos, e.Pos) <= fleeRange {
+			if threat == nil || distance(player.Pos, e.Pos) < distance(player.Pos, threat.Pos) {
+				threat = e
+			}
+		}
+	}
+	if threat == nil {
+		return game.None, false
+	}
+	best, bestDistance := -1, distance(player.Pos, threat.Pos)
+	for i := range directions {
+		pos := step(player.Pos, i)
+		if !passable(level, pos) || enemyAt(level, pos) != nil {
+			continue
+		}
+		if d := distance(pos, threat.Pos); d > bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	if best < 0 {
+		return game.None, false
+	}
+	return directions[best].input, true
+}
+
+// pathTo searches breadth first for the nearest tile matching goal and
+// returns the first step towards it. Enemies block the way except on the
+// goal itself.
+func (b *Bot) pathTo(level *game.Level, goal func(game.Position) bool) (game.InputType, bool) {
+	start := level.Player.Pos
+	first := make(map[game.Position]int)
+	frontier := []game.Position{start}
+	first[start] = -1
+	for len(frontier) > 0 {
+		current := frontier[0]
+		frontier = frontier[1:]
+		for i := range directions {
+			next := step(current, i)
+			if _, seen := first[next]; seen || !passable(level, next) {
+				continue
+			}
+			dir := first[current]
+			if dir < 0 {
+				dir = i
+			}
+			if goal(next) {
+				return directions[dir].input, true
+			}
+			if enemyAt(level, next) != nil {
+				continue
+			}
+			first[next] = dir
+			frontier = append(frontier, next)
+		}
+	}
+	return game.None, false
+}
@@ -0,0 +1,220 @@
+// Package sim plays the game without a window to measure its balance.
+package sim
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/wehard/hive-master/game"
+)
+
+// bandWidth groups enemies by their level for the report, 0-5, 5-10 and so
+// on.
+const bandWidth = 5
+
+// Band holds the totals for the enemies whose level falls in
+// [Min, Min+bandWidth). DamageDealt is what they did to the player,
+// DamageTaken what the player did to them.
+type Band struct {
+	Min         int
+	Enemies     int
+	Kills       int
+	DamageDealt int
+	DamageTaken int
+}
+
+// Result is the outcome of one game.
+type Result struct {
+	Seed        int64
+	Survived    bool
+	Turns       int
+	Kills       int
+	DamageTaken int
+	Bands       map[int]*Band
+}
+
+// headless is a GameUI that feeds a Player and keeps score.
+type headless struct {
+	player   Player
+	maxTurns int
+	level    *game.Level
+	result   Result
+	counted  map[*game.Character]bool
+}
+
+func bandOf(c *game.Character) int {
+	return int(c.Level) / bandWidth * bandWidth
+}
+
+func (h *headless) band(c *game.Character) *Band {
+	min := bandOf(c)
+	b, ok := h.result.Bands[min]
+	if !ok {
+		b = &Band{Min: min}
+		h.result.Bands[min] = b
+	}
+	return b
+}
+
+func (h *headless) NewCharacterLabel(character *game.Character) {}
+
+func (h *headless) Draw(level *game.Level) {
+	h.level = level
+	for _, e := range level.Enemies {
+		if !h.counted[&e.Character] {
+			h.counted[&e.Character] = true
+			h.band(&e.Character).Enemies++
+		}
+	}
+	player := &level.Player.Character
+	for _, a := range level.Attacks {
+		if a.Defender == player {
+			h.band(a.Attacker).DamageDealt += a.Damage
+			h.result.DamageTaken += a.Damage
+			continue
+		}
+		h.band(a.Defender).DamageTaken += a.Damage
+		if a.Defender.IsDead {
+			h.band(a.Defender).Kills++
+			h.result.Kills++
+		}
+	}
+}
+
+func (h *headless) GetInput() *game.Input {
+	if h.level.Turn >= h.maxTurns {
+		return &game.Input{Type: game.Quit}
+	}
+	return &game.Input{Type: h.player.Next(h.level)}
+}
+
+// Play runs one game of at most maxTurns turns.
+func Play(config game.Config, player Player, maxTurns int) (Result, error) {
+	h := &headless{
+		player:   player,
+		maxTurns: maxTurns,
+		result:   Result{Seed: config.Seed, Bands: make(map[int]*Band)},
+		counted:  make(map[*game.Character]bool),
+	}
+	if err := game.Run(h, config); err != nil {
+		return h.result, err
+	}
+	h.result.Turns = h.level.Turn
+	h.result.Survived = !h.level.Player.IsDead
+	return h.result, nil
+}
+
+// Report sums up many games.
+type Report struct {
+	Games       int
+	Survived    int
+	Turns       int
+	Kills       int
+	DamageTaken int
+	Bands       map[int]*Band
+}
+
+func (r *Report) add(result Result) {
+	r.Games++
+	if result.Survived {
+		r.Survived++
+	}
+	r.Turns += result.Turns
+	r.Kills += result.Kills
+	r.DamageTaken += result.DamageTaken
+	for min, b := range result.Bands {
+		total, ok := r.Bands[min]
+		if !ok {
+			total = &Band{Min: min}
+			r.Bands[min] = total
+		}
+		total.Enemies += b.Enemies
+		total.Kills += b.Kills
+		total.DamageDealt += b.DamageDealt
+		total.DamageTaken += b.DamageTaken
+	}
+}
+
+// Simulate plays games bot games seeded config.Seed, config.Seed+1 and so
+// on, spread over all CPUs. Game messages are discarded while it runs. It
+// reports the first game that fails to start.
+func Simulate(config game.Config, games, maxTurns int) (Report, error) {
+	log := game.Log
+	game.Log = io.Discard
+	defer func() { game.Log = log }()
+
+	type outcome struct {
+		result Result
+		err    error
+	}
+	results := make(chan outcome)
+	seeds := make(chan int64)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for seed := range seeds {
+				c := config
+				c.Seed = seed
+				c.RecordFile = ""
+				result, err := Play(c, NewBot(seed), maxTurns)
+				results <- outcome{result, err}
+			}
+		}()
+	}
+	go func() {
+		for i := 0; i < games; i++ {
+			seeds <- config.Seed + int64(i)
+		}
+		close(seeds)
+		wg.Wait()
+		close(results)
+	}()
+
+	report := Report{Bands: make(map[int]*Band)}
+	var err error
+	for o := range results {
+		if o.err != nil {
+			if err == nil {
+				err = o.err
+			}
+			continue
+		}
+		report.add(o.result)
+	}
+	return report, err
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(total)
+}
+
+func average(n, games int) float64 {
+	if games == 0 {
+		return 0
+	}
+	return float64(n) / float64(games)
+}
+
+func (r Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "games %d  survived %.1f%%  turns %.1f  kills %.1f  damage taken %.1f\n",
+		r.Games, percent(r.Survived, r.Games), average(r.Turns, r.Games), average(r.Kills, r.Games), average(r.DamageTaken, r.Games))
+	mins := make([]int, 0, len(r.Bands))
+	for min := range r.Bands {
+		mins = append(mins, min)
+	}
+	sort.Ints(mins)
+	fmt.Fprintf(w, "%-8s %8s %8s %7s %12s %12s\n", "level", "enemies", "kills", "kill%", "dmg dealt", "dmg taken")
+	for _, min := range mins {
+		b := r.Bands[min]
+		fmt.Fprintf(w, "%-8s %8d %8d %6.1f%% %12.1f %12.1f\n", fmt.Sprintf("%d-%d", min, min+bandWidth),
+			b.Enemies, b.Kills, percent(b.Kills, b.Enemies), average(b.DamageDealt, r.Games), average(b.DamageTaken, r.Games))
+	}
+}
@@ -0,0 +1,47 @@
+package sim
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/wehard/hive-master/game"
+)
+
+func TestSimulateDeterministic(t *testing.T) {
+	config := game.Config{Seed: 11, EnemyCount: 20}
+	first, err := Simulate(config, 3, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Simulate(config, 3, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Games != 3 || first.Turns == 0 {
+		t.Fatalf("report %+v, want 3 games that took turns", first)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("same seed, different reports:\n%+v\n%+v", first, second)
+	}
+}
+
+func TestBotIgnoresSecretDoors(t *testing.T) {
+	// The secret door at 2:3 is the short way to the goal.
+	mapFile := filepath.Join(t.TempDir(), "secret.map")
+	rows := "#####\n#@+.#\n#.#.#\n#...#\n#####\n"
+	if err := os.WriteFile(mapFile, []byte(rows), 0644); err != nil {
+		t.Fatal(err)
+	}
+	level, err := game.LoadLevel(mapFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	level.Player = game.NewPlayer("player", 1, *level.PlayerSpawn)
+	goal := game.Position{X: 3, Y: 1}
+	input, ok := NewBot(1).pathTo(level, func(pos game.Position) bool { return pos == goal })
+	if !ok || input != game.Down {
+		t.Errorf("first step %s, %v, want down around the unfound secret door", input, ok)
+	}
+}
@@ -0,0 +1,177 @@
+package ui
+
+import (
+	"math"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/wehard/hive-master/game"
+)
+
+const (
+	moveDuration  = 120
+	bumpDuration  = 160
+	bumpDistance  = 0.3
+	flashDuration = 200
+	cameraSpeed   = 8.0
+)
+
+// sprite is the on-screen state of a character. The game moves characters
+// a whole tile per turn, the sprite catches up over moveDuration.
+type sprite struct {
+	from       game.Position
+	to         game.Position
+	moveStart  uint32
+	bumpDir    game.Position
+	bumpStart  uint32
+	flashStart uint32
+	hasBumped  bool
+	hasFlashed bool
+}
+
+type camera struct {
+	x, y     float64
+	deadZone float64
+	lastTick uint32
+	placed   bool
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+func progress(start, now, duration uint32) float64 {
+	t := float64(now-start) / float64(duration)
+	return math.Min(math.Max(t, 0), 1)
+}
+
+func (s *sprite) position(now uint32) (float64, float64) {
+	t := progress(s.moveStart, now, moveDuration)
+	x := lerp(float64(s.from.X), float64(s.to.X), t)
+	y := lerp(float64(s.from.Y), float64(s.to.Y), t)
+	if s.hasBumped && now-s.bumpStart < bumpDuration {
+		b := progress(s.bumpStart, now, bumpDuration)
+		d := bumpDistance * (1 - math.Abs(2*b-1))
+		x += float64(s.bumpDir.X) * d
+		y += float64(s.bumpDir.Y) * d
+	}
+	return x, y
+}
+
+func (s *sprite) moveTo(pos game.Position, now uint32) {
+	if pos == s.to {
+		return
+	}
+	x, y := s.position(now)
+	s.from = game.Position{X: int(math.Round(x)), Y: int(math.Round(y))}
+	s.to = pos
+	s.moveStart = now
+}
+
+func (s *sprite) bump(toward game.Position, now uint32) {
+	dir := game.Position{X: sign(toward.X - s.to.X), Y: sign(toward.Y - s.to.Y)}
+	s.bumpDir = dir
+	s.bumpStart = now
+	s.hasBumped = true
+}
+
+func (s *sprite) flash(now uint32) {
+	s.flashStart = now
+	s.hasFlashed = true
+}
+
+func (s *sprite) isFlashing(now uint32) bool {
+	return s.hasFlashed && now-s.flashStart < flashDuration
+}
+
+func sign(x int) int {
+	switch {
+	case x < 0:
+		return -1
+	case x > 0:
+		return 1
+	}
+	return 0
+}
+
+func (ui *UI2d) getSprite(character *game.Character) *sprite {
+	s, ok := ui.sprites[character]
+	if !ok {
+		s = &sprite{from: character.Pos, to: character.Pos}
+		ui.sprites[character] = s
+	}
+	return s
+}
+
+func (ui *UI2d) updateSprites(level *game.Level, now uint32) {
+	ui.getSprite(&level.Player.Character).moveTo(level.Player.Pos, now)
+	for _, enemy := range level.Enemies {
+		ui.getSprite(&enemy.Character).moveTo(enemy.Pos, now)
+	}
+	if level.Turn != ui.animatedTurn {
+		ui.animatedTurn = level.Turn
+		ui.forgetRemoved(level)
+		for _, attack := range level.Attacks {
+			ui.getSprite(attack.Attacker).bump(attack.Defender.Pos, now)
+			ui.getSprite(attack.Defender).flash(now)
+		}
+	}
+}
+
+// forgetRemoved drops the sprites and labels of characters that are no
+// longer on the level, such as dead enemies.
+func (ui *UI2d) forgetRemoved(level *game.Level) {
+	onLevel := map[*game.Character]bool{&level.Player.Character: true}
+	for _, enemy := range level.Enemies {
+		onLevel[&enemy.Character] = true
+	}
+	for character := range ui.sprites {
+		if !onLevel[character] {
+			delete(ui.sprites, character)
+		}
+	}
+	for character, label := range ui.characterLabels {
+		if !onLevel[character] {
+			label.Destroy()
+			delete(ui.characterLabels, character)
+		}
+	}
+}
+
+// follow moves the camera towards the target only once the target leaves
+// the dead zone, easing on both axes so the view never jumps a whole tile.
+func (c *camera) follow(targetX, targetY float64, now uint32) {
+	if !c.placed {
+		c.x, c.y = targetX, targetY
+		c.lastTick = now
+		c.placed = true
+		return
+	}
+	dt := float64(now-c.lastTick) / 1000
+	c.lastTick = now
+	t := math.Min(dt*cameraSpeed, 1)
+	c.x = lerp(c.x, deadZoneTarget(c.x, targetX, c.deadZone), t)
+	c.y = lerp(c.y, deadZoneTarget(c.y, targetY, c.deadZone), t)
+}
+
+func deadZoneTarget(current, target, deadZone float64) float64 {
+	if target > current+deadZone {
+		return target - deadZone
+	}
+	if target < current-deadZone {
+		return target + deadZone
+	}
+	return current
+}
+
+func (ui *UI2d) drawSprite(s *sprite, src *sdl.Rect, light game.Light, now uint32) (int32, int32) {
+	x, y := ui.worldToScreen(s.position(now))
+	destRect := sdl.Rect{X: x, Y: y, W: ui.tileSize, H: ui.tileSize}
+	if s.isFlashing(now) {
+		ui.textureAtlas.SetColorMod(255, 64, 64)
+	} else {
+		ui.textureAtlas.SetColorMod(light.RGB())
+	}
+	ui.renderer.Copy(ui.textureAtlas, src, &destRect)
+	ui.textureAtlas.SetColorMod(255, 255, 255)
+	return destRect.X, destRect.Y
+}
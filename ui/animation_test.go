@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/wehard/hive-master/game"
+)
+
+type fakeLabel struct {
+	destroyed bool
+}
+
+func (l *fakeLabel) SetText(text string) {}
+func (l *fakeLabel) Draw(x, y int32)     {}
+func (l *fakeLabel) Destroy()            { l.destroyed = true }
+
+func TestForgetRemovedCharacters(t *testing.T) {
+	level := game.NewLevel(4, 1)
+	level.Player = game.NewPlayer("player", 1, game.Position{X: 0, Y: 0})
+	alive := game.NewEnemy("alive", 1, game.Position{X: 1, Y: 0})
+	dead := game.NewEnemy("dead", 1, game.Position{X: 2, Y: 0})
+	level.AddEnemy(alive)
+
+	ui := &UI2d{
+		sprites:         make(map[*game.Character]*sprite),
+		characterLabels: make(map[*game.Character]Label),
+	}
+	labels := make(map[*game.Character]*fakeLabel)
+	for _, c := range []*game.Character{&level.Player.Character, &alive.Character, &dead.Character} {
+		ui.getSprite(c)
+		labels[c] = &fakeLabel{}
+		ui.characterLabels[c] = labels[c]
+	}
+
+	ui.forgetRemoved(level)
+	if _, ok := ui.sprites[&dead.Character]; ok {
+		t.Error("sprite of a removed enemy kept")
+	}
+	if _, ok := ui.characterLabels[&dead.Character]; ok || !labels[&dead.Character].destroyed {
+		t.Error("label of a removed enemy kept or not destroyed")
+	}
+	for _, c := range []*game.Character{&level.Player.Character, &alive.Character} {
+		if _, ok := ui.sprites[c]; !ok || labels[c].destroyed {
+			t.Errorf("%s lost its sprite or label", c.Name)
+		}
+	}
+}
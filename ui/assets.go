@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/veandco/go-sdl2/img"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	atlasFile        = "dungeon.png"
+	textureIndexFile = "texture_index.txt"
+	labelFontFile    = "anonymous_pro.ttf"
+)
+
+//go:embed assets/dungeon.png assets/texture_index.txt assets/anonymous_pro.ttf
+var embeddedAssets embed.FS
+
+// openAssets returns the assets in dir, or the ones built into the binary
+// when dir is empty.
+func openAssets(dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+	assets, err := fs.Sub(embeddedAssets, "assets")
+	if err != nil {
+		panic(err)
+	}
+	return assets
+}
+
+func loadTexture(renderer *sdl.Renderer, assets fs.FS, name string) (*sdl.Texture, error) {
+	data, err := fs.ReadFile(assets, name)
+	if err != nil {
+		return nil, err
+	}
+	rw, err := sdl.RWFromMem(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return img.LoadTextureRW(renderer, rw, true)
+}
@@ -0,0 +1,33 @@
+package ui
+
+import "testing"
+
+func TestTextureIndexJunctions(t *testing.T) {
+	index, err := loadTextureIndex(openAssets(""), textureIndexFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cross := index["wall_nswe"]
+	for _, name := range []string{"wall_swe", "wall_nwe", "wall_nse", "wall_nsw"} {
+		if index[name] == cross {
+			t.Errorf("%s uses the cross junction sprite", name)
+		}
+	}
+	if index["wall_fill"] == index["blank"] {
+		t.Error("wall_fill uses the empty blank cell, floor would show through")
+	}
+}
+
+func TestTextureIndexDoors(t *testing.T) {
+	index, err := loadTextureIndex(openAssets(""), textureIndexFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, closed := range []string{"door_closed_v", "door_closed_h"} {
+		for _, open := range []string{"door_open_v", "door_open_h"} {
+			if index[closed] == index[open] {
+				t.Errorf("%s and %s use the same sprite", closed, open)
+			}
+		}
+	}
+}
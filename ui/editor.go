@@ -0,0 +1,383 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/wehard/hive-master/game"
+)
+
+const (
+	editorSightRadius = 50
+	paletteTileSize   = atlasTileSize * 3
+	paletteMargin     = 8
+)
+
+var editorPalette = []game.TileType{
+	game.Floor,
+	game.Wall,
+	game.ClosedDoorV,
+	game.OpenDoorV,
+	game.Hole,
+	game.Brazier,
+	game.ClosedChest,
+	game.OpenChest,
+	game.Blank,
+}
+
+type editor struct {
+	level    *game.Level
+	filename string
+	selected int
+	cursor   game.Position
+	showFOV  bool
+	painting bool
+	erasing  bool
+	message  string
+	status   *label
+	quit     bool
+}
+
+// RunEditor lets the user paint the level with tiles from the palette and
+// save it to filename in the .map format. It returns when the editor
+// window is closed or Escape is pressed.
+func (ui *UI2d) RunEditor(level *game.Level, filename string) error {
+	ed := &editor{level: level, filename: filename}
+	if level.PlayerSpawn != nil {
+		ed.cursor = *level.PlayerSpawn
+	} else {
+		ed.cursor = game.Position{X: level.Width / 2, Y: level.Height / 2}
+	}
+	defer func() {
+		if ed.status != nil {
+			ed.status.Destroy()
+		}
+	}()
+	for !ed.quit {
+		for event := ui.events.WaitEventTimeout(frameDelay); event != nil; event = ui.events.PollEvent() {
+			ui.handleEditorEvent(ed, event)
+		}
+		ui.drawEditor(ed)
+	}
+	return nil
+}
+
+func (ed *editor) inBounds(pos game.Position) bool {
+	return pos.X >= 0 && pos.X < ed.level.Width && pos.Y >= 0 && pos.Y < ed.level.Height
+}
+
+func (ed *editor) moveCursor(dx, dy int) {
+	pos := game.Position{X: ed.cursor.X + dx, Y: ed.cursor.Y + dy}
+	if ed.inBounds(pos) {
+		ed.cursor = pos
+	}
+}
+
+func (ed *editor) removeEnemySpawn(pos game.Position) bool {
+	for i, p := range ed.level.EnemySpawns {
+		if p == pos {
+			ed.level.EnemySpawns = append(ed.level.EnemySpawns[:i], ed.level.EnemySpawns[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (ed *editor) removeSpawnZone(pos game.Position) bool {
+	for i, p := range ed.level.SpawnZone {
+		if p == pos {
+			ed.level.SpawnZone = append(ed.level.SpawnZone[:i], ed.level.SpawnZone[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (ed *editor) paint(t game.TileType) {
+	if !ed.inBounds(ed.cursor) || ed.level.Tile(ed.cursor).TileType == t {
+		return
+	}
+	ed.level.SetTile(ed.cursor, game.Tile{TileType: t})
+	if t != game.Floor {
+		ed.removeEnemySpawn(ed.cursor)
+		ed.removeSpawnZone(ed.cursor)
+		ed.level.RemoveObjects(ed.cursor)
+		if ed.level.PlayerSpawn != nil && *ed.level.PlayerSpawn == ed.cursor {
+			ed.level.PlayerSpawn = nil
+		}
+	}
+	game.Autotile(ed.level)
+}
+
+func (ed *editor) placePlayerSpawn() {
+	if !ed.inBounds(ed.cursor) {
+		return
+	}
+	pos := ed.cursor
+	ed.paint(game.Floor)
+	ed.removeEnemySpawn(pos)
+	ed.level.PlayerSpawn = &pos
+}
+
+func (ed *editor) toggleEnemySpawn() {
+	if !ed.inBounds(ed.cursor) || ed.removeEnemySpawn(ed.cursor) {
+		return
+	}
+	ed.paint(game.Floor)
+	if ed.level.PlayerSpawn != nil && *ed.level.PlayerSpawn == ed.cursor {
+		ed.level.PlayerSpawn = nil
+	}
+	ed.level.EnemySpawns = append(ed.level.EnemySpawns, ed.cursor)
+}
+
+func (ed *editor) toggleSpawnZone() {
+	if !ed.inBounds(ed.cursor) || ed.removeSpawnZone(ed.cursor) {
+		return
+	}
+	ed.paint(game.Floor)
+	ed.level.SpawnZone = append(ed.level.SpawnZone, ed.cursor)
+}
+
+// toggleLock locks or unlocks the closed door under the cursor.
+func (ed *editor) toggleLock() {
+	if !ed.inBounds(ed.cursor) {
+		return
+	}
+	tile := ed.level.Tile(ed.cursor)
+	if tile.TileType == game.ClosedDoorV || tile.TileType == game.ClosedDoorH {
+		tile.Locked = !tile.Locked
+		ed.level.SetTile(ed.cursor, tile)
+	}
+}
+
+func (ed *editor) selectTile(i int) {
+	n := len(editorPalette)
+	ed.selected = ((i % n) + n) % n
+}
+
+func (ed *editor) save() {
+	if err := game.SaveLevelToASCIIFile(ed.level, ed.filename); err != nil {
+		ed.message = err.Error()
+		return
+	}
+	ed.message = "saved " + ed.filename
+}
+
+func (ui *UI2d) handleEditorKey(ed *editor, e *sdl.KeyboardEvent) {
+	if e.Type != sdl.KEYDOWN {
+		return
+	}
+	ctrl := e.Keysym.Mod&sdl.KMOD_CTRL != 0
+	switch e.Keysym.Scancode {
+	case sdl.SCANCODE_ESCAPE:
+		ed.quit = true
+	case sdl.SCANCODE_UP:
+		ed.moveCursor(0, -1)
+	case sdl.SCANCODE_DOWN:
+		ed.moveCursor(0, 1)
+	case sdl.SCANCODE_LEFT:
+		ed.moveCursor(-1, 0)
+	case sdl.SCANCODE_RIGHT:
+		ed.moveCursor(1, 0)
+	case sdl.SCANCODE_SPACE:
+		ed.paint(editorPalette[ed.selected])
+	case sdl.SCANCODE_BACKSPACE:
+		ed.paint(game.Blank)
+	case sdl.SCANCODE_TAB, sdl.SCANCODE_RIGHTBRACKET:
+		ed.selectTile(ed.selected + 1)
+	case sdl.SCANCODE_LEFTBRACKET:
+		ed.selectTile(ed.selected - 1)
+	case sdl.SCANCODE_P:
+		ed.placePlayerSpawn()
+	case sdl.SCANCODE_E:
+		ed.toggleEnemySpawn()
+	case sdl.SCANCODE_Z:
+		ed.toggleSpawnZone()
+	case sdl.SCANCODE_L:
+		ed.toggleLock()
+	case sdl.SCANCODE_F:
+		ed.showFOV = !ed.showFOV
+		ui.mapTexture.destroy()
+	case sdl.SCANCODE_S:
+		if ctrl {
+			ed.save()
+		}
+	case sdl.SCANCODE_F5:
+		ed.save()
+	case sdl.SCANCODE_F11:
+		if e.Repeat == 0 {
+			ui.toggleFullscreen()
+		}
+	case sdl.SCANCODE_KP_PLUS:
+		ui.setZoom(ui.zoom + 1)
+	case sdl.SCANCODE_KP_MINUS:
+		ui.setZoom(ui.zoom - 1)
+	default:
+		if e.Keysym.Scancode >= sdl.SCANCODE_1 && e.Keysym.Scancode <= sdl.SCANCODE_9 {
+			i := int(e.Keysym.Scancode - sdl.SCANCODE_1)
+			if i < len(editorPalette) {
+				ed.selected = i
+			}
+		}
+	}
+}
+
+func (ui *UI2d) handleEditorEvent(ed *editor, event sdl.Event) {
+	switch e := event.(type) {
+	case *sdl.QuitEvent:
+		ed.quit = true
+	case *sdl.KeyboardEvent:
+		ui.handleEditorKey(ed, e)
+	case *sdl.WindowEvent:
+		if e.Event == sdl.WINDOWEVENT_SIZE_CHANGED {
+			ui.updateScreenSize()
+		}
+	case *sdl.RenderEvent:
+		ui.mapTexture.destroy()
+	case *sdl.MouseButtonEvent:
+		if i, over := paletteAt(ui.windowToScreen(e.X, e.Y)); over {
+			// The palette is drawn over the map, clicks on it never paint.
+			ed.painting, ed.erasing = false, false
+			if i >= 0 && e.Button == sdl.BUTTON_LEFT && e.State == sdl.PRESSED {
+				ed.selectTile(i)
+			}
+			return
+		}
+		down := e.State == sdl.PRESSED
+		switch e.Button {
+		case sdl.BUTTON_LEFT:
+			ed.painting = down
+		case sdl.BUTTON_RIGHT:
+			ed.erasing = down
+		}
+		if down {
+			ed.cursor = ui.screenToTile(e.X, e.Y)
+		}
+	case *sdl.MouseMotionEvent:
+		if _, over := paletteAt(ui.windowToScreen(e.X, e.Y)); over {
+			return
+		}
+		ed.cursor = ui.screenToTile(e.X, e.Y)
+	case *sdl.MouseWheelEvent:
+		if e.Y > 0 {
+			ed.selectTile(ed.selected - 1)
+		} else if e.Y < 0 {
+			ed.selectTile(ed.selected + 1)
+		}
+	}
+	if ed.painting {
+		ed.paint(editorPalette[ed.selected])
+	} else if ed.erasing {
+		ed.paint(game.Blank)
+	}
+}
+
+func (ed *editor) statusText() string {
+	fov := "off"
+	if ed.showFOV {
+		fov = "on"
+	}
+	text := fmt.Sprintf("%s  tile: %s  fov: %s  (%d, %d)", ed.filename, editorPalette[ed.selected], fov, ed.cursor.X, ed.cursor.Y)
+	if ed.message != "" {
+		text += "  " + ed.message
+	}
+	return text
+}
+
+// drawTileSprite draws the sprite called name in the texture index.
+func (ui *UI2d) drawTileSprite(name string, rect *sdl.Rect) {
+	srcRect := ui.textureIndex[name]
+	ui.renderer.Copy(ui.textureAtlas, &srcRect, rect)
+}
+
+func (ui *UI2d) drawEditorMarker(name string, pos game.Position) {
+	destRect := ui.tileRect(pos)
+	ui.drawTileSprite(name, &destRect)
+}
+
+func (ui *UI2d) drawSpawnZone(level *game.Level) {
+	ui.renderer.SetDrawColor(255, 64, 64, 64)
+	for _, pos := range level.SpawnZone {
+		rect := ui.tileRect(pos)
+		ui.renderer.FillRect(&rect)
+	}
+}
+
+// paletteRect is where the palette entry i is drawn on the screen.
+func paletteRect(i int) sdl.Rect {
+	return sdl.Rect{
+		X: paletteMargin + int32(i)*(paletteTileSize+paletteMargin),
+		Y: paletteMargin,
+		W: paletteTileSize,
+		H: paletteTileSize,
+	}
+}
+
+// paletteAt reports whether the screen position x, y is over the palette,
+// margins included, and the entry under it or -1 between entries.
+func paletteAt(x, y int32) (int, bool) {
+	last := paletteRect(len(editorPalette) - 1)
+	if x < 0 || y < 0 || x >= last.X+last.W+paletteMargin || y >= last.Y+last.H+paletteMargin {
+		return -1, false
+	}
+	for i := range editorPalette {
+		r := paletteRect(i)
+		if x >= r.X && x < r.X+r.W && y >= r.Y && y < r.Y+r.H {
+			return i, true
+		}
+	}
+	return -1, true
+}
+
+func (ui *UI2d) drawPalette(ed *editor) {
+	for i, t := range editorPalette {
+		destRect := paletteRect(i)
+		ui.drawTileSprite(game.Floor.String(), &destRect)
+		ui.drawTileSprite(t.String(), &destRect)
+		if i == ed.selected {
+			ui.renderer.SetDrawColor(255, 255, 0, 255)
+			ui.renderer.DrawRect(&destRect)
+		}
+	}
+}
+
+func (ui *UI2d) drawEditor(ed *editor) {
+	level := ed.level
+	if ed.showFOV && level.PlayerSpawn != nil {
+		game.PreviewFOV(level, *level.PlayerSpawn, editorSightRadius)
+	} else {
+		game.RevealLevel(level)
+	}
+	now := sdl.GetTicks()
+	ui.cam.follow(float64(ed.cursor.X), float64(ed.cursor.Y), now)
+	ui.centerOn(ui.cam.x, ui.cam.y)
+
+	ui.renderer.SetDrawColor(0, 0, 0, 255)
+	ui.renderer.Clear()
+	ui.drawMap(level)
+	ui.drawObjects(level)
+	ui.drawSpawnZone(level)
+	ui.textureAtlas.SetColorMod(255, 255, 255)
+	ui.textureAtlas.SetAlphaMod(160)
+	for _, pos := range level.EnemySpawns {
+		ui.drawEditorMarker("enemy", pos)
+	}
+	if level.PlayerSpawn != nil {
+		ui.drawEditorMarker("player", *level.PlayerSpawn)
+	}
+	ui.textureAtlas.SetAlphaMod(255)
+
+	cursorRect := ui.tileRect(ed.cursor)
+	ui.renderer.SetDrawColor(255, 255, 0, 255)
+	ui.renderer.DrawRect(&cursorRect)
+	ui.drawPalette(ed)
+
+	text := ed.statusText()
+	if ed.status == nil {
+		ed.status = NewLabel(text, ui.renderer, ui.labelFont, ui.text)
+	} else {
+		ed.status.SetText(text)
+	}
+	ed.status.Draw(ui.screenWidth/2, ui.screenHeight-32)
+	ui.renderer.Present()
+}
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/wehard/hive-master/game"
+)
+
+func TestEditorIgnoresCursorOffMap(t *testing.T) {
+	level := game.NewLevel(3, 3)
+	ed := &editor{level: level, cursor: game.Position{X: -1, Y: 5}}
+	ed.placePlayerSpawn()
+	ed.toggleEnemySpawn()
+	ed.toggleSpawnZone()
+	if level.PlayerSpawn != nil || len(level.EnemySpawns) != 0 || len(level.SpawnZone) != 0 {
+		t.Errorf("spawns placed off the map: player %v, enemies %v, zone %v", level.PlayerSpawn, level.EnemySpawns, level.SpawnZone)
+	}
+
+	ed.cursor = game.Position{X: 1, Y: 1}
+	ed.placePlayerSpawn()
+	if level.PlayerSpawn == nil || *level.PlayerSpawn != ed.cursor {
+		t.Errorf("player spawn at %v, want %v", level.PlayerSpawn, ed.cursor)
+	}
+}
+
+func TestPaletteAt(t *testing.T) {
+	second := paletteRect(1)
+	last := paletteRect(len(editorPalette) - 1)
+	tests := []struct {
+		x, y int32
+		i    int
+		over bool
+	}{
+		{second.X, second.Y, 1, true},
+		{second.X + second.W - 1, second.Y + second.H - 1, 1, true},
+		{second.X - 1, second.Y, -1, true},
+		{0, 0, -1, true},
+		{last.X + last.W/2, last.Y + last.H/2, len(editorPalette) - 1, true},
+		{last.X + last.W + paletteMargin, last.Y, -1, false},
+		{second.X, second.Y + second.H + paletteMargin, -1, false},
+	}
+	for _, test := range tests {
+		if i, over := paletteAt(test.x, test.y); i != test.i || over != test.over {
+			t.Errorf("paletteAt(%d, %d) = %d, %v, want %d, %v", test.x, test.y, i, over, test.i, test.over)
+		}
+	}
+}
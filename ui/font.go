@@ -0,0 +1,125 @@
+package ui
+
+import (
+	"fmt"
+	"io/fs"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+type fontKey struct {
+	path string
+	size int
+}
+
+// fontManager opens each font file and size once and shares it between
+// everything that draws text. SDL_ttf reads font data lazily, so the file
+// contents are kept for as long as the fonts are open.
+type fontManager struct {
+	assets fs.FS
+	data   map[string][]byte
+	fonts  map[fontKey]*ttf.Font
+}
+
+func newFontManager(assets fs.FS) *fontManager {
+	return &fontManager{
+		assets: assets,
+		data:   make(map[string][]byte),
+		fonts:  make(map[fontKey]*ttf.Font),
+	}
+}
+
+func (m *fontManager) get(path string, size int) (*ttf.Font, error) {
+	key := fontKey{path, size}
+	if font, ok := m.fonts[key]; ok {
+		return font, nil
+	}
+	data, ok := m.data[path]
+	if !ok {
+		var err error
+		data, err = fs.ReadFile(m.assets, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open font: %v", err)
+		}
+		m.data[path] = data
+	}
+	rw, err := sdl.RWFromMem(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open font %s: %v", path, err)
+	}
+	font, err := ttf.OpenFontRW(rw, 1, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open font %s: %v", path, err)
+	}
+	m.fonts[key] = font
+	return font, nil
+}
+
+func (m *fontManager) close() {
+	for key, font := range m.fonts {
+		font.Close()
+		delete(m.fonts, key)
+	}
+	m.data = make(map[string][]byte)
+}
+
+type textKey struct {
+	font *ttf.Font
+	text string
+	fg   sdl.Color
+	bg   sdl.Color
+}
+
+type textTexture struct {
+	key     textKey
+	texture *sdl.Texture
+	w, h    int32
+	refs    int
+}
+
+// textCache keeps one texture per distinct string and colour pair. Entries
+// are reference counted and destroyed when the last label lets go of them.
+type textCache struct {
+	renderer *sdl.Renderer
+	entries  map[textKey]*textTexture
+}
+
+func newTextCache(renderer *sdl.Renderer) *textCache {
+	return &textCache{renderer: renderer, entries: make(map[textKey]*textTexture)}
+}
+
+func (c *textCache) acquire(key textKey) (*textTexture, error) {
+	if entry, ok := c.entries[key]; ok {
+		entry.refs++
+		return entry, nil
+	}
+	s, err := key.font.RenderUTF8Shaded(key.text, key.fg, key.bg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create font surface: %v", err)
+	}
+	defer s.Free()
+	texture, err := c.renderer.CreateTextureFromSurface(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create font texture from surface: %v", err)
+	}
+	entry := &textTexture{key: key, texture: texture, w: s.W, h: s.H, refs: 1}
+	c.entries[key] = entry
+	return entry, nil
+}
+
+func (c *textCache) release(entry *textTexture) {
+	entry.refs--
+	if entry.refs > 0 {
+		return
+	}
+	entry.texture.Destroy()
+	delete(c.entries, entry.key)
+}
+
+func (c *textCache) destroy() {
+	for key, entry := range c.entries {
+		entry.texture.Destroy()
+		delete(c.entries, key)
+	}
+}
@@ -0,0 +1,180 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/wehard/hive-master/game"
+)
+
+const (
+	stickDeadZone  = 8000
+	repeatDelay    = 300
+	repeatInterval = 120
+)
+
+type controllerOpener interface {
+	open(index int) (sdl.JoystickID, error)
+	close(id sdl.JoystickID)
+}
+
+type sdlControllers map[sdl.JoystickID]*sdl.GameController
+
+func (c sdlControllers) open(index int) (sdl.JoystickID, error) {
+	if !sdl.IsGameController(index) {
+		return 0, fmt.Errorf("joystick %d is not a game controller", index)
+	}
+	controller := sdl.GameControllerOpen(index)
+	if controller == nil {
+		return 0, fmt.Errorf("failed to open game controller %d", index)
+	}
+	id := controller.Joystick().InstanceID()
+	c[id] = controller
+	return id, nil
+}
+
+func (c sdlControllers) close(id sdl.JoystickID) {
+	if controller, ok := c[id]; ok {
+		controller.Close()
+		delete(c, id)
+	}
+}
+
+// gamepad turns game controller events into game inputs. Controllers are
+// opened and closed as they are plugged in and out, and a held direction
+// repeats after repeatDelay milliseconds every repeatInterval milliseconds.
+type gamepad struct {
+	controllers controllerOpener
+	connected   map[sdl.JoystickID]bool
+	stickX      int16
+	stickY      int16
+	stickInput  game.InputType
+	held        game.InputType
+	nextRepeat  uint32
+}
+
+func newGamepad(controllers controllerOpener) *gamepad {
+	return &gamepad{
+		controllers: controllers,
+		connected:   make(map[sdl.JoystickID]bool),
+	}
+}
+
+func controllerButtonInput(button uint8) game.InputType {
+	switch button {
+	case sdl.CONTROLLER_BUTTON_DPAD_UP:
+		return game.Up
+	case sdl.CONTROLLER_BUTTON_DPAD_DOWN:
+		return game.Down
+	case sdl.CONTROLLER_BUTTON_DPAD_LEFT:
+		return game.Left
+	case sdl.CONTROLLER_BUTTON_DPAD_RIGHT:
+		return game.Right
+	case sdl.CONTROLLER_BUTTON_A:
+		return game.Action
+	case sdl.CONTROLLER_BUTTON_X:
+		return game.Search
+	case sdl.CONTROLLER_BUTTON_RIGHTSHOULDER:
+		return game.ZoomIn
+	case sdl.CONTROLLER_BUTTON_LEFTSHOULDER:
+		return game.ZoomOut
+	case sdl.CONTROLLER_BUTTON_BACK:
+		return game.Quit
+	}
+	return game.None
+}
+
+func isDirection(t game.InputType) bool {
+	return t == game.Up || t == game.Down || t == game.Left || t == game.Right
+}
+
+func (pad *gamepad) stickDirection() game.InputType {
+	x, y := int(pad.stickX), int(pad.stickY)
+	if abs(x) < stickDeadZone && abs(y) < stickDeadZone {
+		return game.None
+	}
+	if abs(x) > abs(y) {
+		if x < 0 {
+			return game.Left
+		}
+		return game.Right
+	}
+	if y < 0 {
+		return game.Up
+	}
+	return game.Down
+}
+
+func (pad *gamepad) press(t game.InputType, now uint32) game.InputType {
+	if isDirection(t) {
+		pad.held = t
+		pad.nextRepeat = now + repeatDelay
+	}
+	return t
+}
+
+func (pad *gamepad) release(t game.InputType) {
+	if pad.held == t {
+		pad.held = game.None
+	}
+}
+
+func (pad *gamepad) handleEvent(event sdl.Event, now uint32) game.InputType {
+	switch e := event.(type) {
+	case *sdl.ControllerDeviceEvent:
+		if e.Type == sdl.CONTROLLERDEVICEADDED {
+			id, err := pad.controllers.open(int(e.Which))
+			if err != nil {
+				fmt.Println(err)
+				return game.None
+			}
+			pad.connected[id] = true
+		} else if e.Type == sdl.CONTROLLERDEVICEREMOVED {
+			pad.controllers.close(e.Which)
+			delete(pad.connected, e.Which)
+			pad.held = game.None
+			pad.stickX, pad.stickY = 0, 0
+			pad.stickInput = game.None
+		}
+	case *sdl.ControllerButtonEvent:
+		t := controllerButtonInput(e.Button)
+		if e.State == sdl.PRESSED {
+			return pad.press(t, now)
+		}
+		pad.release(t)
+	case *sdl.ControllerAxisEvent:
+		switch e.Axis {
+		case sdl.CONTROLLER_AXIS_LEFTX:
+			pad.stickX = e.Value
+		case sdl.CONTROLLER_AXIS_LEFTY:
+			pad.stickY = e.Value
+		default:
+			return game.None
+		}
+		t := pad.stickDirection()
+		if t == pad.stickInput {
+			return game.None
+		}
+		pad.release(pad.stickInput)
+		pad.stickInput = t
+		if t != game.None {
+			return pad.press(t, now)
+		}
+	}
+	return game.None
+}
+
+func (pad *gamepad) repeat(now uint32) game.InputType {
+	if pad.held == game.None || now < pad.nextRepeat {
+		return game.None
+	}
+	pad.nextRepeat = now + repeatInterval
+	return pad.held
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/wehard/hive-master/game"
+)
+
+// fakeControllers opens every device index as the joystick id index+100,
+// except the ones listed in broken.
+type fakeControllers struct {
+	opened map[sdl.JoystickID]bool
+	broken map[int]bool
+}
+
+func newFakeControllers() *fakeControllers {
+	return &fakeControllers{opened: make(map[sdl.JoystickID]bool), broken: make(map[int]bool)}
+}
+
+func (c *fakeControllers) open(index int) (sdl.JoystickID, error) {
+	if c.broken[index] {
+		return 0, fmt.Errorf("joystick %d is not a game controller", index)
+	}
+	id := sdl.JoystickID(index + 100)
+	c.opened[id] = true
+	return id, nil
+}
+
+func (c *fakeControllers) close(id sdl.JoystickID) {
+	delete(c.opened, id)
+}
+
+func button(b uint8, state uint8) *sdl.ControllerButtonEvent {
+	return &sdl.ControllerButtonEvent{Button: b, State: state}
+}
+
+func axis(a uint8, value int16) *sdl.ControllerAxisEvent {
+	return &sdl.ControllerAxisEvent{Axis: a, Value: value}
+}
+
+func TestGamepadButtons(t *testing.T) {
+	tests := []struct {
+		button uint8
+		want   game.InputType
+	}{
+		{sdl.CONTROLLER_BUTTON_DPAD_UP, game.Up},
+		{sdl.CONTROLLER_BUTTON_DPAD_DOWN, game.Down},
+		{sdl.CONTROLLER_BUTTON_DPAD_LEFT, game.Left},
+		{sdl.CONTROLLER_BUTTON_DPAD_RIGHT, game.Right},
+		{sdl.CONTROLLER_BUTTON_A, game.Action},
+		{sdl.CONTROLLER_BUTTON_X, game.Search},
+		{sdl.CONTROLLER_BUTTON_BACK, game.Quit},
+	}
+	for _, test := range tests {
+		pad := newGamepad(newFakeControllers())
+		if got := pad.handleEvent(button(test.button, sdl.PRESSED), 0); got != test.want {
+			t.Errorf("button %d pressed = %s, want %s", test.button, got, test.want)
+		}
+		if got := pad.handleEvent(button(test.button, sdl.RELEASED), 10); got != game.None {
+			t.Errorf("button %d released = %s, want none", test.button, got)
+		}
+		if pad.held != game.None {
+			t.Errorf("button %d still held after release", test.button)
+		}
+	}
+}
+
+func TestGamepadStick(t *testing.T) {
+	pad := newGamepad(newFakeControllers())
+	steps := []struct {
+		event sdl.Event
+		want  game.InputType
+	}{
+		{axis(sdl.CONTROLLER_AXIS_LEFTX, stickDeadZone/2), game.None},
+		{axis(sdl.CONTROLLER_AXIS_LEFTX, 30000), game.Right},
+		{axis(sdl.CONTROLLER_AXIS_LEFTX, 31000), game.None},
+		{axis(sdl.CONTROLLER_AXIS_LEFTY, -32000), game.Up},
+		{axis(sdl.CONTROLLER_AXIS_LEFTX, -32767), game.Left},
+		{axis(sdl.CONTROLLER_AXIS_LEFTY, 0), game.None},
+		{axis(sdl.CONTROLLER_AXIS_LEFTX, 0), game.None},
+	}
+	for i, step := range steps {
+		if got := pad.handleEvent(step.event, 0); got != step.want {
+			t.Errorf("step %d: input %s, want %s", i, got, step.want)
+		}
+	}
+	if pad.held != game.None {
+		t.Errorf("stick back in the middle still holds %s", pad.held)
+	}
+}
+
+func TestGamepadRepeat(t *testing.T) {
+	pad := newGamepad(newFakeControllers())
+	pad.handleEvent(button(sdl.CONTROLLER_BUTTON_DPAD_LEFT, sdl.PRESSED), 1000)
+	if got := pad.repeat(1000 + repeatDelay - 1); got != game.None {
+		t.Errorf("repeat before the delay = %s, want none", got)
+	}
+	if got := pad.repeat(1000 + repeatDelay); got != game.Left {
+		t.Errorf("repeat after the delay = %s, want left", got)
+	}
+	if got := pad.repeat(1000 + repeatDelay + repeatInterval - 1); got != game.None {
+		t.Errorf("repeat within the interval = %s, want none", got)
+	}
+	if got := pad.repeat(1000 + repeatDelay + repeatInterval); got != game.Left {
+		t.Errorf("repeat after the interval = %s, want left", got)
+	}
+	pad.handleEvent(button(sdl.CONTROLLER_BUTTON_DPAD_LEFT, sdl.RELEASED), 2000)
+	if got := pad.repeat(5000); got != game.None {
+		t.Errorf("repeat after release = %s, want none", got)
+	}
+
+	pad.handleEvent(button(sdl.CONTROLLER_BUTTON_A, sdl.PRESSED), 0)
+	if got := pad.repeat(5000); got != game.None {
+		t.Errorf("action repeated as %s", got)
+	}
+}
+
+func TestGamepadHotplug(t *testing.T) {
+	controllers := newFakeControllers()
+	controllers.broken[3] = true
+	pad := newGamepad(controllers)
+
+	pad.handleEvent(&sdl.ControllerDeviceEvent{Type: sdl.CONTROLLERDEVICEADDED, Which: 0}, 0)
+	pad.handleEvent(&sdl.ControllerDeviceEvent{Type: sdl.CONTROLLERDEVICEADDED, Which: 3}, 0)
+	if !pad.connected[100] || !controllers.opened[100] || len(pad.connected) != 1 {
+		t.Fatalf("connected %v, open %v, want only controller 100", pad.connected, controllers.opened)
+	}
+
+	pad.handleEvent(axis(sdl.CONTROLLER_AXIS_LEFTX, 32000), 0)
+	pad.handleEvent(&sdl.ControllerDeviceEvent{Type: sdl.CONTROLLERDEVICEREMOVED, Which: 100}, 0)
+	if len(pad.connected) != 0 || len(controllers.opened) != 0 {
+		t.Errorf("connected %v, open %v after removal, want none", pad.connected, controllers.opened)
+	}
+	if got := pad.repeat(10000); got != game.None {
+		t.Errorf("unplugged controller still repeats %s", got)
+	}
+}
@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/wehard/hive-master/game"
+)
+
+const (
+	frameRate  = 60
+	frameDelay = 1000 / frameRate
+)
+
+type eventSource interface {
+	PollEvent() sdl.Event
+	WaitEventTimeout(timeout int) sdl.Event
+}
+
+type sdlEventSource struct{}
+
+func (sdlEventSource) PollEvent() sdl.Event {
+	return sdl.PollEvent()
+}
+
+func (sdlEventSource) WaitEventTimeout(timeout int) sdl.Event {
+	return sdl.WaitEventTimeout(timeout)
+}
+
+var keyInputs = map[sdl.Scancode]game.InputType{
+	sdl.SCANCODE_ESCAPE:   game.Quit,
+	sdl.SCANCODE_UP:       game.Up,
+	sdl.SCANCODE_DOWN:     game.Down,
+	sdl.SCANCODE_LEFT:     game.Left,
+	sdl.SCANCODE_RIGHT:    game.Right,
+	sdl.SCANCODE_SPACE:    game.Action,
+	sdl.SCANCODE_S:        game.Search,
+	sdl.SCANCODE_KP_PLUS:  game.ZoomIn,
+	sdl.SCANCODE_KP_MINUS: game.ZoomOut,
+}
+
+func (ui *UI2d) keyboardInput(e *sdl.KeyboardEvent) game.InputType {
+	if e.Type != sdl.KEYDOWN {
+		return game.None
+	}
+	if e.Keysym.Scancode == sdl.SCANCODE_F11 && e.Repeat == 0 {
+		ui.toggleFullscreen()
+		return game.None
+	}
+	if e.Keysym.Scancode == sdl.SCANCODE_F3 && e.Repeat == 0 {
+		ui.frameTimer.visible = !ui.frameTimer.visible
+		return game.None
+	}
+	t := keyInputs[e.Keysym.Scancode]
+	if e.Repeat != 0 && !isDirection(t) {
+		return game.None
+	}
+	return t
+}
+
+func (ui *UI2d) handleEvent(event sdl.Event) game.InputType {
+	switch e := event.(type) {
+	case *sdl.QuitEvent:
+		return game.Quit
+	case *sdl.KeyboardEvent:
+		return ui.keyboardInput(e)
+	case *sdl.WindowEvent:
+		switch e.Event {
+		case sdl.WINDOWEVENT_SIZE_CHANGED:
+			ui.updateScreenSize()
+		case sdl.WINDOWEVENT_LEAVE:
+			ui.pointer.inside = false
+		}
+		return game.None
+	case *sdl.RenderEvent:
+		ui.mapTexture.destroy()
+		return game.None
+	case *sdl.MouseMotionEvent, *sdl.MouseButtonEvent:
+		ui.mouseInput(event)
+		return game.None
+	}
+	return ui.pad.handleEvent(event, sdl.GetTicks())
+}
+
+// GetInput blocks until the player gives an input. While waiting it keeps
+// redrawing the last drawn level at frameRate so animations stay alive
+// without spinning on the event queue.
+func (ui *UI2d) GetInput() *game.Input {
+	nextFrame := sdl.GetTicks() + frameDelay
+	for {
+		var input game.Input
+		now := sdl.GetTicks()
+		if now >= nextFrame {
+			if ui.currentLevel != nil {
+				ui.Draw(ui.currentLevel)
+			}
+			nextFrame = now + frameDelay
+		}
+		timeout := int(nextFrame - now)
+		if ui.pad.held != game.None && ui.pad.nextRepeat < nextFrame {
+			timeout = int(ui.pad.nextRepeat) - int(now)
+		}
+		if ui.travel.active() && int(ui.travel.nextStep)-int(now) < timeout {
+			timeout = int(ui.travel.nextStep) - int(now)
+		}
+		if timeout < 0 {
+			timeout = 0
+		}
+		// Stop at the first input so the events after it stay queued for
+		// the next turn.
+		for event := ui.events.WaitEventTimeout(timeout); event != nil; event = ui.events.PollEvent() {
+			if input.Type = ui.handleEvent(event); input.Type != game.None {
+				break
+			}
+		}
+		if input.Type == game.None {
+			input.Type = ui.pad.repeat(sdl.GetTicks())
+		}
+		if input.Type != game.None {
+			ui.travel.stop()
+		} else {
+			input.Type = ui.travelStep(sdl.GetTicks())
+		}
+		switch input.Type {
+		case game.ZoomIn:
+			ui.setZoom(ui.zoom + 1)
+		case game.ZoomOut:
+			ui.setZoom(ui.zoom - 1)
+		}
+		if input.Type != game.None {
+			return &input
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/wehard/hive-master/game"
+)
+
+// fakeEvents hands out queued events, as if they all arrived in one frame.
+type fakeEvents struct {
+	queue []sdl.Event
+}
+
+func (f *fakeEvents) PollEvent() sdl.Event {
+	if len(f.queue) == 0 {
+		return nil
+	}
+	e := f.queue[0]
+	f.queue = f.queue[1:]
+	return e
+}
+
+func (f *fakeEvents) WaitEventTimeout(timeout int) sdl.Event {
+	return f.PollEvent()
+}
+
+func keyDown(code sdl.Scancode) *sdl.KeyboardEvent {
+	return &sdl.KeyboardEvent{Type: sdl.KEYDOWN, Keysym: sdl.Keysym{Scancode: code}}
+}
+
+func TestGetInputKeepsQueuedEvents(t *testing.T) {
+	events := &fakeEvents{queue: []sdl.Event{
+		keyDown(sdl.SCANCODE_LEFT),
+		keyDown(sdl.SCANCODE_UP),
+		&sdl.QuitEvent{Type: sdl.QUIT},
+	}}
+	ui := &UI2d{events: events, pad: newGamepad(newFakeControllers())}
+	for _, want := range []game.InputType{game.Left, game.Up, game.Quit} {
+		if got := ui.GetInput().Type; got != want {
+			t.Errorf("input %s, want %s", got, want)
+		}
+	}
+}
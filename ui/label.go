@@ -3,54 +3,59 @@ package ui
 import (
 	"fmt"
 
-	"github.com/wehard/hive-master/game"
-
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/veandco/go-sdl2/ttf"
 )
 
 type Label interface {
 	SetText(text string)
-	Draw(pos game.Position)
+	Draw(x, y int32)
+	Destroy()
 }
 
 type label struct {
-	text string
-	r    *sdl.Renderer
-	font *ttf.Font
+	text     string
+	r        *sdl.Renderer
+	font     *ttf.Font
+	cache    *textCache
+	texture  *textTexture
+	destRect sdl.Rect
 }
 
-func NewLabel(text string, r *sdl.Renderer) *label {
-	font, err := ttf.OpenFont("ui/assets/anonymous_pro.ttf", 20)
-	if err != nil {
-		panic(err)
-	}
-	return &label{text, r, font}
+func NewLabel(text string, r *sdl.Renderer, font *ttf.Font, cache *textCache) *label {
+	return &label{text: text, r: r, font: font, cache: cache}
 }
 
-func (l *label) Draw(pos game.Position) {
-	s, err := l.font.RenderUTF8Shaded(l.text, sdl.Color{255, 255, 255, 255}, sdl.Color{0, 0, 0, 255})
-	if err != nil {
-		fmt.Println("failed to create font surface:", err)
-		return
-	}
-	defer s.Free()
-	var clipRect sdl.Rect
-	s.GetClipRect(&clipRect)
-	texture, err := l.r.CreateTextureFromSurface(s)
-	if err != nil {
-		fmt.Println("failed to create font texture from surface:", err)
-		return
+func (l *label) Draw(x, y int32) {
+	if l.texture == nil {
+		key := textKey{l.font, l.text, sdl.Color{R: 255, G: 255, B: 255, A: 255}, sdl.Color{R: 0, G: 0, B: 0, A: 255}}
+		texture, err := l.cache.acquire(key)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		l.texture = texture
 	}
-	destRect := &sdl.Rect{
-		X: int32(pos.X)*tileSize + offsetX - clipRect.W/2 + tileSize/2,
-		Y: int32(pos.Y)*tileSize + offsetY - tileSize/2,
-		W: clipRect.W,
-		H: clipRect.H,
+	l.destRect = sdl.Rect{
+		X: x - l.texture.w/2,
+		Y: y,
+		W: l.texture.w,
+		H: l.texture.h,
 	}
-	l.r.Copy(texture, nil, destRect)
+	l.r.Copy(l.texture.texture, nil, &l.destRect)
 }
 
 func (l *label) SetText(text string) {
+	if text == l.text {
+		return
+	}
 	l.text = text
+	l.Destroy()
+}
+
+func (l *label) Destroy() {
+	if l.texture != nil {
+		l.cache.release(l.texture)
+		l.texture = nil
+	}
 }
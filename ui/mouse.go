@@ -0,0 +1,188 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/wehard/hive-master/game"
+)
+
+// travelInterval is the time between the steps of a click-to-move, long
+// enough for each step to finish animating.
+const travelInterval = moveDuration
+
+// pointer is the last mouse position in window coordinates.
+type pointer struct {
+	x, y   int32
+	inside bool
+}
+
+// travel walks the player to a clicked tile one step at a time. It stops
+// when the player gives any other input, when the way is blocked and when
+// an enemy that was not in view when it started comes into view.
+type travel struct {
+	level    *game.Level
+	path     []game.Position
+	seen     map[*game.Enemy]bool
+	nextStep uint32
+	// from is where the player stood when the last step was given, tries
+	// how many steps were given from there. Opening a door takes one.
+	from  game.Position
+	tries int
+}
+
+func (t *travel) active() bool {
+	return len(t.path) > 0
+}
+
+func (t *travel) stop() {
+	*t = travel{}
+}
+
+func (ui *UI2d) mouseInput(event sdl.Event) {
+	switch e := event.(type) {
+	case *sdl.MouseMotionEvent:
+		ui.pointer = pointer{e.X, e.Y, true}
+	case *sdl.MouseButtonEvent:
+		ui.pointer = pointer{e.X, e.Y, true}
+		if e.Button == sdl.BUTTON_LEFT && e.State == sdl.PRESSED {
+			ui.startTravel(ui.screenToTile(e.X, e.Y))
+		}
+	}
+}
+
+func (ui *UI2d) startTravel(goal game.Position) {
+	level := ui.currentLevel
+	ui.travel.stop()
+	if level == nil || level.Player.IsDead {
+		return
+	}
+	path := game.PlayerPath(level, goal)
+	if len(path) == 0 {
+		return
+	}
+	seen := make(map[*game.Enemy]bool)
+	for _, enemy := range level.Enemies {
+		if !enemy.IsDead && level.IsVisible(enemy.Pos) {
+			seen[enemy] = true
+		}
+	}
+	ui.travel = travel{
+		level:    level,
+		path:     path,
+		seen:     seen,
+		nextStep: sdl.GetTicks(),
+		from:     level.Player.Pos,
+	}
+}
+
+// travelStep returns the next step of a click-to-move once it is due.
+func (ui *UI2d) travelStep(now uint32) game.InputType {
+	t := &ui.travel
+	if !t.active() || now < t.nextStep {
+		return game.None
+	}
+	level := ui.currentLevel
+	if level != t.level {
+		t.stop()
+		return game.None
+	}
+	player := level.Player
+	if player.Pos == t.path[0] {
+		t.path = t.path[1:]
+	}
+	if player.Pos != t.from {
+		t.from = player.Pos
+		t.tries = 0
+	}
+	if !t.active() || t.tries == 2 || level.EnemyAt(t.path[0]) != nil || ui.enemyCameIntoView(level) {
+		t.stop()
+		return game.None
+	}
+	input := stepInput(player.Pos, t.path[0])
+	if input == game.None {
+		t.stop()
+		return game.None
+	}
+	t.tries++
+	t.nextStep = now + travelInterval
+	return input
+}
+
+func (ui *UI2d) enemyCameIntoView(level *game.Level) bool {
+	for _, enemy := range level.Enemies {
+		if !enemy.IsDead && level.IsVisible(enemy.Pos) && !ui.travel.seen[enemy] {
+			return true
+		}
+	}
+	return false
+}
+
+// stepInput is the direction input that moves from one tile to the next,
+// or None when they are not next to each other.
+func stepInput(from, to game.Position) game.InputType {
+	switch (game.Position{X: to.X - from.X, Y: to.Y - from.Y}) {
+	case game.Position{X: 0, Y: -1}:
+		return game.Up
+	case game.Position{X: 0, Y: 1}:
+		return game.Down
+	case game.Position{X: -1, Y: 0}:
+		return game.Left
+	case game.Position{X: 1, Y: 0}:
+		return game.Right
+	}
+	return game.None
+}
+
+// drawTooltip names the tile under the mouse, or the enemy standing on it.
+func (ui *UI2d) drawTooltip(level *game.Level) {
+	if !ui.pointer.inside {
+		return
+	}
+	text := tooltipText(level, ui.screenToTile(ui.pointer.x, ui.pointer.y))
+	if text == "" {
+		return
+	}
+	if ui.tooltip == nil {
+		ui.tooltip = NewLabel(text, ui.renderer, ui.labelFont, ui.text)
+	} else {
+		ui.tooltip.SetText(text)
+	}
+	x, y := ui.windowToScreen(ui.pointer.x, ui.pointer.y)
+	ui.tooltip.Draw(x, y+ui.tileSize/2)
+}
+
+func tooltipText(level *game.Level, pos game.Position) string {
+	if !level.IsVisited(pos) {
+		return ""
+	}
+	if enemy := level.EnemyAt(pos); enemy != nil && !enemy.IsDead && level.IsVisible(pos) {
+		return fmt.Sprintf("%s lv:%.2f hp:%d", enemy.Name, enemy.Level, enemy.Health)
+	}
+	return tileName(level.Tile(pos))
+}
+
+func tileName(tile game.Tile) string {
+	switch tile.TileType {
+	case game.Blank:
+		return ""
+	case game.Floor:
+		return "floor"
+	case game.Hole:
+		return "hole"
+	case game.ClosedDoorV, game.ClosedDoorH:
+		if tile.Locked {
+			return "locked door"
+		}
+		return "closed door"
+	case game.OpenDoorV, game.OpenDoorH:
+		return "open door"
+	case game.Brazier:
+		return "brazier"
+	case game.ClosedChest:
+		return "chest"
+	case game.OpenChest:
+		return "open chest"
+	}
+	return "wall"
+}
@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const frameTimeInterval = 500
+
+// frameTimer averages the time spent in Draw and shows it in the top left
+// corner. The label text only changes every frameTimeInterval milliseconds
+// so the overlay itself does not churn the text cache.
+type frameTimer struct {
+	visible    bool
+	total      uint64
+	frames     int
+	lastUpdate uint32
+	label      *label
+}
+
+func (ui *UI2d) recordFrameTime(start uint64, now uint32) {
+	t := &ui.frameTimer
+	t.total += sdl.GetPerformanceCounter() - start
+	t.frames++
+	if now-t.lastUpdate < frameTimeInterval {
+		return
+	}
+	ms := float64(t.total) / float64(t.frames) * 1000 / float64(sdl.GetPerformanceFrequency())
+	text := fmt.Sprintf("frame %6.2f ms", ms)
+	if t.label == nil {
+		t.label = NewLabel(text, ui.renderer, ui.labelFont, ui.text)
+	} else {
+		t.label.SetText(text)
+	}
+	t.total = 0
+	t.frames = 0
+	t.lastUpdate = now
+}
+
+func (ui *UI2d) drawFrameTime() {
+	if ui.frameTimer.visible && ui.frameTimer.label != nil {
+		ui.frameTimer.label.Draw(100, 8)
+	}
+}
@@ -0,0 +1,191 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/wehard/hive-master/game"
+)
+
+// rememberedShade is the brightness of tiles that have been explored but
+// are not currently in view.
+const rememberedShade = 64
+
+// lockedTint is drawn over locked doors.
+var lockedTint = sdl.Color{R: 160, G: 32, B: 32, A: 96}
+
+// mapTexture is a render target holding every explored tile of a level at
+// atlas resolution. Only the tiles the level reports as changed are redrawn
+// into it, so a frame costs one copy for the remembered map plus one copy
+// per run of equally lit visible tiles.
+type mapTexture struct {
+	texture *sdl.Texture
+	level   *game.Level
+	baked   []game.Tile
+	// full redraws every tile once, after the texture was created.
+	full bool
+}
+
+// destroy frees the texture. It is also called when SDL reports that render
+// targets or the device were reset, which loses the texture's contents, so
+// the next frame builds it again.
+func (m *mapTexture) destroy() {
+	if m.texture != nil {
+		m.texture.Destroy()
+		m.texture = nil
+	}
+	m.level = nil
+	m.baked = nil
+}
+
+func (ui *UI2d) resetMapTexture(level *game.Level) error {
+	ui.mapTexture.destroy()
+	texture, err := ui.renderer.CreateTexture(sdl.PIXELFORMAT_RGBA8888, sdl.TEXTUREACCESS_TARGET,
+		int32(level.Width*atlasTileSize), int32(level.Height*atlasTileSize))
+	if err != nil {
+		return fmt.Errorf("failed to create map texture: %v", err)
+	}
+	texture.SetBlendMode(sdl.BLENDMODE_BLEND)
+	ui.renderer.SetRenderTarget(texture)
+	ui.renderer.SetDrawColor(0, 0, 0, 0)
+	ui.renderer.Clear()
+	ui.renderer.SetRenderTarget(nil)
+
+	ui.mapTexture.texture = texture
+	ui.mapTexture.level = level
+	ui.mapTexture.baked = make([]game.Tile, level.Width*level.Height)
+	ui.mapTexture.full = true
+	return nil
+}
+
+func (ui *UI2d) updateMapTexture(level *game.Level) error {
+	if ui.mapTexture.level != level {
+		if err := ui.resetMapTexture(level); err != nil {
+			return err
+		}
+	}
+	changed, all := level.TakeChanged()
+	if all || ui.mapTexture.full {
+		ui.mapTexture.full = false
+		changed = changed[:0]
+		for y := 0; y < level.Height; y++ {
+			for x := 0; x < level.Width; x++ {
+				changed = append(changed, game.Position{X: x, Y: y})
+			}
+		}
+	}
+	floorRect := ui.textureIndex[game.Floor.String()]
+	target := false
+	for _, pos := range changed {
+		tile := level.Tile(pos)
+		baked := &ui.mapTexture.baked[pos.Y*level.Width+pos.X]
+		if !level.IsVisited(pos) || *baked == tile {
+			continue
+		}
+		if !target {
+			ui.renderer.SetRenderTarget(ui.mapTexture.texture)
+			target = true
+		}
+		destRect := sdl.Rect{
+			X: int32(pos.X * atlasTileSize),
+			Y: int32(pos.Y * atlasTileSize),
+			W: atlasTileSize,
+			H: atlasTileSize,
+		}
+		if tile.TileType == game.Blank {
+			ui.renderer.SetDrawBlendMode(sdl.BLENDMODE_NONE)
+			ui.renderer.SetDrawColor(0, 0, 0, 0)
+			ui.renderer.FillRect(&destRect)
+			ui.renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+		} else {
+			srcRect := ui.textureIndex[tile.TileType.String()]
+			ui.renderer.Copy(ui.textureAtlas, &floorRect, &destRect)
+			ui.renderer.Copy(ui.textureAtlas, &srcRect, &destRect)
+			if tile.Locked {
+				ui.renderer.SetDrawColor(lockedTint.R, lockedTint.G, lockedTint.B, lockedTint.A)
+				ui.renderer.FillRect(&destRect)
+			}
+		}
+		*baked = tile
+	}
+	if target {
+		ui.renderer.SetRenderTarget(nil)
+	}
+	return nil
+}
+
+func floorDiv(a, b int32) int32 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// visibleTileRect returns the range of tiles [x0, x1) x [y0, y1) that
+// overlap the screen.
+func (ui *UI2d) visibleTileRect(level *game.Level) (x0, y0, x1, y1 int) {
+	topLeft := ui.tileAt(0, 0)
+	bottomRight := ui.tileAt(ui.screenWidth, ui.screenHeight)
+	x0, y0 = topLeft.X, topLeft.Y
+	x1, y1 = bottomRight.X+1, bottomRight.Y+1
+	if x0 < 0 {
+		x0 = 0
+	}
+	if y0 < 0 {
+		y0 = 0
+	}
+	if x1 > level.Width {
+		x1 = level.Width
+	}
+	if y1 > level.Height {
+		y1 = level.Height
+	}
+	return
+}
+
+func (ui *UI2d) copyMapRegion(x, y, w, h int) {
+	srcRect := sdl.Rect{
+		X: int32(x * atlasTileSize),
+		Y: int32(y * atlasTileSize),
+		W: int32(w * atlasTileSize),
+		H: int32(h * atlasTileSize),
+	}
+	destRect := ui.tileRect(game.Position{X: x, Y: y})
+	destRect.W *= int32(w)
+	destRect.H *= int32(h)
+	ui.renderer.Copy(ui.mapTexture.texture, &srcRect, &destRect)
+}
+
+func (ui *UI2d) drawMap(level *game.Level) {
+	if err := ui.updateMapTexture(level); err != nil {
+		fmt.Println(err)
+		return
+	}
+	x0, y0, x1, y1 := ui.visibleTileRect(level)
+	if x0 >= x1 || y0 >= y1 {
+		return
+	}
+	ui.mapTexture.texture.SetColorMod(rememberedShade, rememberedShade, rememberedShade)
+	ui.copyMapRegion(x0, y0, x1-x0, y1-y0)
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; {
+			if !level.IsVisible(game.Position{X: x, Y: y}) {
+				x++
+				continue
+			}
+			start := x
+			r, g, b := level.LightAt(game.Position{X: x, Y: y}).RGB()
+			for x < x1 && level.IsVisible(game.Position{X: x, Y: y}) {
+				nr, ng, nb := level.LightAt(game.Position{X: x, Y: y}).RGB()
+				if nr != r || ng != g || nb != b {
+					break
+				}
+				x++
+			}
+			ui.mapTexture.texture.SetColorMod(r, g, b)
+			ui.copyMapRegion(start, y, x-start, 1)
+		}
+	}
+	ui.mapTexture.texture.SetColorMod(255, 255, 255)
+}
@@ -3,222 +3,295 @@ package ui
 import (
 	"bufio"
 	"fmt"
-	"os"
+	"io/fs"
 	"strconv"
 	"strings"
 
-	"github.com/veandco/go-sdl2/img"
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/veandco/go-sdl2/ttf"
 	"github.com/wehard/hive-master/game"
 )
 
+type Config struct {
+	WindowTitle    string
+	Width          int32
+	Height         int32
+	CameraDeadZone float64
+	ShowFrameTime  bool
+	// AssetDir overrides the assets built into the binary.
+	AssetDir string
+}
+
 type UI2d struct {
-	WindowTitle string
+	config          Config
+	assets          fs.FS
+	renderer        *sdl.Renderer
+	window          *sdl.Window
+	textureAtlas    *sdl.Texture
+	textureIndex    map[string]sdl.Rect
+	offsetX         int32
+	offsetY         int32
+	characterLabels map[*game.Character]Label
+	fonts           *fontManager
+	text            *textCache
+	labelFont       *ttf.Font
+	tileSize        int32
+	zoom            int32
+	screenWidth     int32
+	screenHeight    int32
+	events          eventSource
+	currentLevel    *game.Level
+	pad             *gamepad
+	cam             camera
+	sprites         map[*game.Character]*sprite
+	animatedTurn    int
+	mapTexture      mapTexture
+	frameTimer      frameTimer
+	pointer         pointer
+	travel          travel
+	tooltip         *label
+	sdlStarted      bool
 }
 
 const (
 	winWidth, winHeight = 1920, 1080
 )
 
-var renderer *sdl.Renderer
-var window *sdl.Window
-var textureAtlas *sdl.Texture
-var textureIndex map[game.TileType]sdl.Rect
-var keyboardState []uint8
-var prevKeyboardState []uint8
-var centerX int
-var centerY int
-var offsetX int32
-var offsetY int32
-var characterLabels map[*game.Character]Label
-var tileSize int32 = 32
-
-func (ui *UI2d) GetTextureIndex(tileType game.TileType) *sdl.Rect {
-	i := textureIndex[tileType]
-	return &i
-}
+// sdlUsers counts open UI2d instances so SDL is initialised by the first
+// and shut down by the last.
+var sdlUsers int
 
-func (ui *UI2d) GetTextureAtlas() *sdl.Texture {
-	return textureAtlas
+func startSDL() error {
+	if sdlUsers == 0 {
+		if err := sdl.Init(sdl.INIT_EVERYTHING); err != nil {
+			return err
+		}
+		if err := ttf.Init(); err != nil {
+			sdl.Quit()
+			return err
+		}
+	}
+	sdlUsers++
+	return nil
 }
 
-func (ui *UI2d) NewCharacterLabel(character *game.Character) {
-	s := character.Name + " lv:" + fmt.Sprintf("%.2f", character.Level)
-	characterLabels[character] = NewLabel(s, renderer)
+func stopSDL() {
+	sdlUsers--
+	if sdlUsers == 0 {
+		ttf.Quit()
+		sdl.Quit()
+	}
 }
 
-func init() {
-	if err := sdl.Init(sdl.INIT_EVERYTHING); err != nil {
-		panic(err)
+func NewUI2d(config Config) (*UI2d, error) {
+	if config.WindowTitle == "" {
+		config.WindowTitle = "Hive Master"
 	}
+	if config.Width == 0 || config.Height == 0 {
+		config.Width, config.Height = winWidth, winHeight
+	}
+	assets := openAssets(config.AssetDir)
+	ui := &UI2d{
+		config:          config,
+		assets:          assets,
+		characterLabels: make(map[*game.Character]Label),
+		fonts:           newFontManager(assets),
+		sprites:         make(map[*game.Character]*sprite),
+		events:          sdlEventSource{},
+		pad:             newGamepad(sdlControllers{}),
+	}
+	ui.cam.deadZone = config.CameraDeadZone
+	ui.frameTimer.visible = config.ShowFrameTime
 
-	if err := ttf.Init(); err != nil {
-		panic(err)
+	if err := startSDL(); err != nil {
+		return nil, fmt.Errorf("failed to initialise SDL: %v", err)
 	}
+	ui.sdlStarted = true
 
-	window, err := sdl.CreateWindow("Hive Master", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
-		winWidth, winHeight, sdl.WINDOW_SHOWN)
+	var err error
+	ui.window, err = sdl.CreateWindow(config.WindowTitle, sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
+		config.Width, config.Height, sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE|sdl.WINDOW_ALLOW_HIGHDPI)
 	if err != nil {
-		panic(err)
+		ui.Close()
+		return nil, fmt.Errorf("failed to create window: %v", err)
 	}
 
 	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "0")
-	renderer, err = sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
+	ui.renderer, err = sdl.CreateRenderer(ui.window, -1, sdl.RENDERER_ACCELERATED|sdl.RENDERER_TARGETTEXTURE)
 	if err != nil {
-		panic(err)
+		ui.Close()
+		return nil, fmt.Errorf("failed to create renderer: %v", err)
 	}
 
-	textureAtlas, err = img.LoadTexture(renderer, "ui/assets/dungeon.png")
+	ui.textureAtlas, err = loadTexture(ui.renderer, ui.assets, atlasFile)
+	if err != nil {
+		ui.Close()
+		return nil, fmt.Errorf("failed to load texture atlas: %v", err)
+	}
+	ui.textureAtlas.SetBlendMode(sdl.BLENDMODE_BLEND)
+	ui.textureIndex, err = loadTextureIndex(ui.assets, textureIndexFile)
+	if err != nil {
+		ui.Close()
+		return nil, err
+	}
+	ui.labelFont, err = ui.fonts.get(labelFontFile, 20)
 	if err != nil {
-		fmt.Println(err)
+		ui.Close()
+		return nil, err
 	}
-	textureAtlas.SetBlendMode(sdl.BLENDMODE_BLEND)
-	loadTextureIndex("ui/assets/texture_index.txt")
+	ui.text = newTextCache(ui.renderer)
+	ui.screenWidth, ui.screenHeight = config.Width, config.Height
+	ui.updateScreenSize()
+	ui.setZoom(defaultZoom * ui.pixelRatio())
+	return ui, nil
+}
 
-	keyboardState = sdl.GetKeyboardState()
-	prevKeyboardState = make([]uint8, len(keyboardState))
-	for i, v := range keyboardState {
-		prevKeyboardState[i] = v
+// Close releases everything NewUI2d created. It is safe to call on a
+// partially constructed UI.
+func (ui *UI2d) Close() {
+	for _, label := range ui.characterLabels {
+		label.Destroy()
+	}
+	ui.characterLabels = make(map[*game.Character]Label)
+	if ui.frameTimer.label != nil {
+		ui.frameTimer.label.Destroy()
+		ui.frameTimer.label = nil
+	}
+	if ui.tooltip != nil {
+		ui.tooltip.Destroy()
+		ui.tooltip = nil
+	}
+	ui.mapTexture.destroy()
+	if ui.text != nil {
+		ui.text.destroy()
+	}
+	ui.fonts.close()
+	if ui.textureAtlas != nil {
+		ui.textureAtlas.Destroy()
+		ui.textureAtlas = nil
+	}
+	if ui.renderer != nil {
+		ui.renderer.Destroy()
+		ui.renderer = nil
+	}
+	if ui.window != nil {
+		ui.window.Destroy()
+		ui.window = nil
+	}
+	if ui.sdlStarted {
+		stopSDL()
+		ui.sdlStarted = false
 	}
-	centerX = -1
-	centerY = -1
-	characterLabels = make(map[*game.Character]Label)
 }
 
-func loadTextureIndex(filename string) {
-	file, err := os.Open(filename)
+func (ui *UI2d) NewCharacterLabel(character *game.Character) {
+	s := character.Name + " lv:" + fmt.Sprintf("%.2f", character.Level)
+	ui.characterLabels[character] = NewLabel(s, ui.renderer, ui.labelFont, ui.text)
+}
+
+func loadTextureIndex(assets fs.FS, filename string) (map[string]sdl.Rect, error) {
+	file, err := assets.Open(filename)
 	if err != nil {
-		fmt.Println(err)
+		return nil, fmt.Errorf("failed to open texture index: %v", err)
 	}
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
-	textureIndex = make(map[game.TileType]sdl.Rect)
+	textureIndex := make(map[string]sdl.Rect)
+	lineNumber := 0
 	for scanner.Scan() {
+		lineNumber++
 		line := scanner.Text()
 		line = strings.TrimSpace(line)
-		split := strings.Split(line, ",")
-		var tile game.Tile
-		tile.TileType = getTileType(strings.TrimSpace(split[0]))
-		x, err := strconv.ParseInt(strings.TrimSpace(split[1]), 10, 64)
-		if err != nil {
-			panic(err)
-		}
-		y, err := strconv.ParseInt(strings.TrimSpace(split[2]), 10, 64)
-		if err != nil {
-			panic(err)
+		if line == "" {
+			continue
 		}
-		w, err := strconv.ParseInt(strings.TrimSpace(split[3]), 10, 64)
-		if err != nil {
-			panic(err)
+		split := strings.Split(line, ",")
+		if len(split) != 5 {
+			return nil, fmt.Errorf("%s:%d: expected 5 fields, got %d", filename, lineNumber, len(split))
 		}
-		h, err := strconv.ParseInt(strings.TrimSpace(split[4]), 10, 64)
-		if err != nil {
-			panic(err)
+		var values [4]int64
+		for i := range values {
+			values[i], err = strconv.ParseInt(strings.TrimSpace(split[i+1]), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", filename, lineNumber, err)
+			}
 		}
-		tileIndexX := x
-		tileIndexY := y
-		tileRect := sdl.Rect{X: int32(tileIndexX * 16), Y: int32(tileIndexY * 16), W: int32(w), H: int32(h)}
-		textureIndex[tile.TileType] = tileRect
+		tileIndexX := values[0]
+		tileIndexY := values[1]
+		tileRect := sdl.Rect{X: int32(tileIndexX * atlasTileSize), Y: int32(tileIndexY * atlasTileSize), W: int32(values[2]), H: int32(values[3])}
+		textureIndex[strings.TrimSpace(split[0])] = tileRect
 	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read texture index: %v", err)
+	}
+	return textureIndex, nil
 }
 
-func getTileType(s string) game.TileType {
-	return game.TileType(s)
-}
-
-func (ui UI2d) Draw(level *game.Level) {
-	if centerX == -1 && centerY == -1 {
-		centerX = level.Player.Pos.X
-		centerY = level.Player.Pos.Y
-	}
-	moveThreshold := 4
-	if level.Player.Pos.X > centerX+moveThreshold {
-		centerX++
-	} else if level.Player.Pos.X < centerX-moveThreshold {
-		centerX--
-	} else if level.Player.Pos.Y > centerY+moveThreshold {
-		centerY++
-	} else if level.Player.Pos.Y < centerY-moveThreshold {
-		centerY--
-	}
-
-	offsetX = int32((winWidth / 2) - int32(centerX)*tileSize)
-	offsetY = int32((winHeight / 2) - int32(centerY)*tileSize)
-	renderer.Clear()
-	for y, row := range level.Map {
-		for x, tile := range row {
-			if tile.TileType != game.Blank && level.Visited[y][x] {
-				srcRect := textureIndex[level.Map[y][x].TileType]
-				destRect := sdl.Rect{
-					X: int32(x*int(tileSize)) + offsetX,
-					Y: int32(y*int(tileSize)) + offsetY,
-					W: tileSize,
-					H: tileSize,
-				}
-				//pos := game.Position{x, y}
-				if level.Visited[y][x] {
-					textureAtlas.SetColorMod(128, 128, 128)
-				}
-				if level.Visible[y][x] {
-					textureAtlas.SetColorMod(255, 255, 255)
-				}
-				floorRect := textureIndex[game.Floor]
-				renderer.Copy(textureAtlas, &floorRect, &destRect)
-				renderer.Copy(textureAtlas, &srcRect, &destRect)
-			}
+// drawObjects marks explored keys, switches and found traps with a small
+// square in the middle of their tile.
+func (ui *UI2d) drawObjects(level *game.Level) {
+	draw := func(pos game.Position, r, g, b uint8) {
+		if !level.IsVisited(pos) {
+			return
 		}
+		rect := ui.tileRect(pos)
+		size := ui.tileSize / 3
+		ui.renderer.SetDrawColor(r, g, b, 255)
+		ui.renderer.FillRect(&sdl.Rect{X: rect.X + size, Y: rect.Y + size, W: size, H: size})
 	}
-	textureAtlas.SetColorMod(255, 255, 255)
-	for _, enemy := range level.Enemies {
-		if !enemy.IsDead && level.Visible[enemy.Pos.Y][enemy.Pos.X] {
-			//textureAtlas.SetColorMod(255, 0, 0)
-			enemy.Draw(renderer, tileSize, offsetX, offsetY)
-			label := characterLabels[&enemy.Character]
-			label.Draw(enemy.Pos)
-			//textureAtlas.SetColorMod(255, 255, 255)
+	for pos := range level.Keys {
+		draw(pos, 255, 210, 40)
+	}
+	for _, s := range level.Switches {
+		if s.On {
+			draw(s.Pos, 80, 220, 80)
+		} else {
+			draw(s.Pos, 150, 150, 150)
+		}
+	}
+	for _, trap := range level.Traps {
+		if !trap.Found {
+			continue
 		}
+		c := trapColors[trap.Type]
+		draw(trap.Pos, c.R, c.G, c.B)
 	}
-	level.Player.Draw(renderer, tileSize, offsetX, offsetY)
-	label := characterLabels[&level.Player.Character]
-	label.Draw(level.Player.Pos)
-	renderer.Present()
 }
 
-func (ui *UI2d) GetInput() *game.Input {
-	for {
-		var input game.Input
-		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-			switch event.(type) {
-			case *sdl.QuitEvent:
-				return &game.Input{Type: game.Quit}
-			}
-		}
-		if keyboardState[sdl.SCANCODE_ESCAPE] == 1 && prevKeyboardState[sdl.SCANCODE_ESCAPE] == 0 {
-			input.Type = game.Quit
-		} else if keyboardState[sdl.SCANCODE_UP] == 1 && prevKeyboardState[sdl.SCANCODE_UP] == 0 {
-			input.Type = game.Up
-		} else if keyboardState[sdl.SCANCODE_DOWN] == 1 && prevKeyboardState[sdl.SCANCODE_DOWN] == 0 {
-			input.Type = game.Down
-		} else if keyboardState[sdl.SCANCODE_LEFT] == 1 && prevKeyboardState[sdl.SCANCODE_LEFT] == 0 {
-			input.Type = game.Left
-		} else if keyboardState[sdl.SCANCODE_RIGHT] == 1 && prevKeyboardState[sdl.SCANCODE_RIGHT] == 0 {
-			input.Type = game.Right
-		} else if keyboardState[sdl.SCANCODE_SPACE] == 1 && prevKeyboardState[sdl.SCANCODE_SPACE] == 0 {
-			input.Type = game.Action
-		} else if keyboardState[sdl.SCANCODE_KP_PLUS] == 1 && prevKeyboardState[sdl.SCANCODE_KP_PLUS] == 0 {
-			input.Type = game.ZoomIn
-			tileSize++
-		} else if keyboardState[sdl.SCANCODE_KP_MINUS] == 1 && prevKeyboardState[sdl.SCANCODE_KP_MINUS] == 0 {
-			input.Type = game.ZoomOut
-			tileSize--
-		}
-		for i, v := range keyboardState {
-			prevKeyboardState[i] = v
-		}
-		if input.Type != game.None {
-			return &input
+var trapColors = map[game.TrapType]sdl.Color{
+	game.SpikeTrap:    {R: 200, G: 40, B: 40, A: 255},
+	game.AlarmTrap:    {R: 240, G: 140, B: 20, A: 255},
+	game.TeleportTrap: {R: 160, G: 60, B: 220, A: 255},
+}
+
+func (ui *UI2d) Draw(level *game.Level) {
+	start := sdl.GetPerformanceCounter()
+	ui.currentLevel = level
+	now := sdl.GetTicks()
+	ui.updateSprites(level, now)
+	px, py := ui.getSprite(&level.Player.Character).position(now)
+	ui.cam.follow(px, py, now)
+
+	ui.centerOn(ui.cam.x, ui.cam.y)
+	ui.renderer.SetDrawColor(0, 0, 0, 255)
+	ui.renderer.Clear()
+	ui.drawMap(level)
+	ui.drawObjects(level)
+	enemyRect := ui.textureIndex["enemy"]
+	playerRect := ui.textureIndex["player"]
+	for _, enemy := range level.Enemies {
+		if !enemy.IsDead && level.IsVisible(enemy.Pos) {
+			x, y := ui.drawSprite(ui.getSprite(&enemy.Character), &enemyRect, level.LightAt(enemy.Pos), now)
+			label := ui.characterLabels[&enemy.Character]
+			label.Draw(x+ui.tileSize/2, y-ui.tileSize/2)
 		}
 	}
+	x, y := ui.drawSprite(ui.getSprite(&level.Player.Character), &playerRect, level.LightAt(level.Player.Pos), now)
+	label := ui.characterLabels[&level.Player.Character]
+	label.Draw(x+ui.tileSize/2, y-ui.tileSize/2)
+	ui.drawTooltip(level)
+	ui.recordFrameTime(start, now)
+	ui.drawFrameTime()
+	ui.renderer.Present()
 }
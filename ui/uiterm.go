@@ -1,18 +1,143 @@
 package ui
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/wehard/hive-master/game"
+	"golang.org/x/term"
 )
 
-type UITerm struct{}
+const (
+	termViewWidth  = 60
+	termViewHeight = 20
+	clearScreen    = "\x1b[H\x1b[2J"
+)
+
+var termInputs = map[rune]game.InputType{
+	'w': game.Up,
+	's': game.Down,
+	'a': game.Left,
+	'd': game.Right,
+	'e': game.Action,
+	'f': game.Search,
+	' ': game.Action,
+	'q': game.Quit,
+	// Ctrl-C, which raw mode delivers as a key instead of a signal.
+	'\x03': game.Quit,
+}
+
+// arrowInputs maps the final byte of the ANSI arrow key sequences.
+var arrowInputs = map[rune]game.InputType{
+	'A': game.Up,
+	'B': game.Down,
+	'C': game.Right,
+	'D': game.Left,
+}
+
+// UITerm plays the game as text. The view around the player is written to
+// out and one command per key is read from in, wasd or the arrow keys to
+// move, e or space for the action and q to quit. When in is a terminal it
+// is put in raw mode so keys arrive without Enter; Close restores it.
+type UITerm struct {
+	in       *bufio.Reader
+	out      io.Writer
+	tty      *os.File
+	ttyState *term.State
+}
+
+func NewUITerm(in io.Reader, out io.Writer) *UITerm {
+	ui := &UITerm{in: bufio.NewReader(in), out: out}
+	if file, ok := in.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		state, err := term.MakeRaw(int(file.Fd()))
+		if err != nil {
+			fmt.Println("failed to set raw mode:", err)
+		} else {
+			ui.tty = file
+			ui.ttyState = state
+		}
+	}
+	return ui
+}
+
+func (ui *UITerm) Close() {
+	if ui.ttyState != nil {
+		term.Restore(int(ui.tty.Fd()), ui.ttyState)
+		ui.ttyState = nil
+	}
+}
+
+func (ui *UITerm) NewCharacterLabel(character *game.Character) {}
 
-func (ui UITerm) Draw(level *game.Level) {
-	for y, row := range level.Map {
-		for x, _ := range row {
-			fmt.Printf("%c", level.Map[y][x])
+func (ui *UITerm) Draw(level *game.Level) {
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	characters := make(map[game.Position]rune)
+	for _, enemy := range level.Enemies {
+		if !enemy.IsDead {
+			characters[enemy.Pos] = 'e'
+		}
+	}
+	characters[level.Player.Pos] = '@'
+
+	left := level.Player.Pos.X - termViewWidth/2
+	top := level.Player.Pos.Y - termViewHeight/2
+	for y := top; y < top+termViewHeight; y++ {
+		line := make([]rune, 0, termViewWidth)
+		for x := left; x < left+termViewWidth; x++ {
+			line = append(line, termRune(level, game.Position{X: x, Y: y}, characters))
+		}
+		b.WriteString(strings.TrimRight(string(line), " "))
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "%s lv:%.2f hp:%d keys:%d turn:%d\n", level.Player.Name, level.Player.Level, level.Player.Health, len(level.Player.Keys), level.Turn)
+	screen := b.String()
+	if ui.ttyState != nil {
+		// Raw mode no longer turns a newline into a carriage return too.
+		screen = strings.ReplaceAll(screen, "\n", "\r\n")
+	}
+	io.WriteString(ui.out, screen)
+}
+
+func termRune(level *game.Level, pos game.Position, characters map[game.Position]rune) rune {
+	if !level.IsVisited(pos) {
+		return ' '
+	}
+	if c, ok := characters[pos]; ok && level.IsVisible(pos) {
+		return c
+	}
+	if c, ok := level.ObjectRune(pos); ok {
+		return c
+	}
+	return level.TileRune(pos)
+}
+
+// GetInput returns Quit once in is exhausted so a scripted game always ends.
+func (ui *UITerm) GetInput() *game.Input {
+	for {
+		r, _, err := ui.in.ReadRune()
+		if err != nil {
+			return &game.Input{Type: game.Quit}
+		}
+		// A bare escape arrives on its own, an arrow key sequence in one
+		// read, so only look further when more input is already buffered.
+		if r == '\x1b' && ui.in.Buffered() > 0 {
+			if next, _, err := ui.in.ReadRune(); err != nil || next != '[' {
+				ui.in.UnreadRune()
+				continue
+			}
+			if code, _, err := ui.in.ReadRune(); err == nil {
+				if t, ok := arrowInputs[code]; ok {
+					return &game.Input{Type: t}
+				}
+			}
+			continue
+		}
+		if t, ok := termInputs[r]; ok {
+			return &game.Input{Type: t}
 		}
-		fmt.Print("\n")
 	}
 }
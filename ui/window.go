@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/wehard/hive-master/game"
+)
+
+const (
+	atlasTileSize = 16
+	defaultZoom   = 2
+	minZoom       = 1
+	maxZoom       = 8
+)
+
+// setZoom scales tiles by whole multiples of the atlas tile size so pixel
+// art is never resampled by a fractional factor.
+func (ui *UI2d) setZoom(z int32) {
+	if z < minZoom {
+		z = minZoom
+	}
+	if z > maxZoom {
+		z = maxZoom
+	}
+	ui.zoom = z
+	ui.tileSize = atlasTileSize * ui.zoom
+}
+
+// pixelRatio is the number of drawable pixels per window unit, 2 on most
+// high-DPI displays.
+func (ui *UI2d) pixelRatio() int32 {
+	w, _ := ui.window.GetSize()
+	if w == 0 || ui.screenWidth < w {
+		return 1
+	}
+	return ui.screenWidth / w
+}
+
+func (ui *UI2d) updateScreenSize() {
+	w, h, err := ui.renderer.GetOutputSize()
+	if err != nil {
+		fmt.Println("failed to get renderer output size:", err)
+		return
+	}
+	ui.screenWidth, ui.screenHeight = w, h
+}
+
+func (ui *UI2d) toggleFullscreen() {
+	var flags uint32
+	if ui.window.GetFlags()&sdl.WINDOW_FULLSCREEN == 0 {
+		flags = sdl.WINDOW_FULLSCREEN_DESKTOP
+	}
+	if err := ui.window.SetFullscreen(flags); err != nil {
+		fmt.Println("failed to toggle fullscreen:", err)
+		return
+	}
+	ui.updateScreenSize()
+}
+
+// Positions on the map are in tiles and positions on the screen in
+// drawable pixels. The camera offset and the zoom level only meet in the
+// functions below.
+
+// centerOn places the camera so the map position x, y is in the middle of
+// the screen.
+func (ui *UI2d) centerOn(x, y float64) {
+	ui.offsetX = ui.screenWidth/2 - int32(x*float64(ui.tileSize))
+	ui.offsetY = ui.screenHeight/2 - int32(y*float64(ui.tileSize))
+}
+
+// worldToScreen converts a map position, fractional while sprites move, to
+// the screen.
+func (ui *UI2d) worldToScreen(x, y float64) (int32, int32) {
+	return int32(x*float64(ui.tileSize)) + ui.offsetX, int32(y*float64(ui.tileSize)) + ui.offsetY
+}
+
+// tileRect is the part of the screen the tile at pos covers.
+func (ui *UI2d) tileRect(pos game.Position) sdl.Rect {
+	x, y := ui.worldToScreen(float64(pos.X), float64(pos.Y))
+	return sdl.Rect{X: x, Y: y, W: ui.tileSize, H: ui.tileSize}
+}
+
+// windowToScreen converts window coordinates, as reported by mouse events,
+// to drawable pixels.
+func (ui *UI2d) windowToScreen(x, y int32) (int32, int32) {
+	ratio := ui.pixelRatio()
+	return x * ratio, y * ratio
+}
+
+// tileAt is the map tile under the screen position x, y, the inverse of
+// tileRect.
+func (ui *UI2d) tileAt(x, y int32) game.Position {
+	return game.Position{
+		X: int(floorDiv(x-ui.offsetX, ui.tileSize)),
+		Y: int(floorDiv(y-ui.offsetY, ui.tileSize)),
+	}
+}
+
+// screenToTile converts a position in window coordinates to the map tile
+// under it.
+func (ui *UI2d) screenToTile(x, y int32) game.Position {
+	return ui.tileAt(ui.windowToScreen(x, y))
+}
@@ -0,0 +1,30 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/wehard/hive-master/game"
+)
+
+func TestTileAtInvertsTileRect(t *testing.T) {
+	ui := &UI2d{tileSize: 48, screenWidth: 640, screenHeight: 480}
+	ui.centerOn(3.5, -2.25)
+	for _, pos := range []game.Position{{X: 0, Y: 0}, {X: 3, Y: -2}, {X: -5, Y: 7}} {
+		r := ui.tileRect(pos)
+		for _, corner := range [][2]int32{{r.X, r.Y}, {r.X + r.W - 1, r.Y + r.H - 1}} {
+			if got := ui.tileAt(corner[0], corner[1]); got != pos {
+				t.Errorf("tileAt(%d, %d) = %v, want %v", corner[0], corner[1], got, pos)
+			}
+		}
+	}
+}
+
+func TestVisibleTileRect(t *testing.T) {
+	level := game.NewLevel(100, 100)
+	ui := &UI2d{tileSize: 32, screenWidth: 320, screenHeight: 160}
+	ui.offsetX, ui.offsetY = -48, 16
+	x0, y0, x1, y1 := ui.visibleTileRect(level)
+	if x0 != 1 || y0 != 0 || x1 != 12 || y1 != 5 {
+		t.Errorf("visible tiles [%d, %d) x [%d, %d), want [1, 12) x [0, 5)", x0, x1, y0, y1)
+	}
+}